@@ -0,0 +1,108 @@
+package capture
+
+import (
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Attributes
+// @Description: The item data read off the page besides price
+type Attributes struct {
+	Title string `json:"title,omitempty"`
+	// Seller is the user name of seller
+	Seller string `json:"seller,omitempty"`
+	// SellerFeedback is the feedback of seller, exp: 99.8% positive feedback
+	SellerFeedback string `json:"sellerFeedback,omitempty"`
+	// Condition is the item condition, exp: New, Used
+	Condition string `json:"condition,omitempty"`
+	// QuantityAvailable is nil if the page does not show it, "More than 10 available" is 10
+	QuantityAvailable *int `json:"quantityAvailable,omitempty"`
+	// QuantitySold is nil if the page does not show it
+	QuantitySold *int `json:"quantitySold,omitempty"`
+	// ItemLocation is where the item ships from
+	ItemLocation string `json:"itemLocation,omitempty"`
+	// Returns is the returns policy, exp: 30 days returns. Buyer pays for return shipping
+	Returns string `json:"returns,omitempty"`
+}
+
+// EBAY_ATTRIBUTE_XPATHS is the xpaths of every attribute, tried in order like EBAY_PRICE_XPATHS
+var EBAY_ATTRIBUTE_XPATHS = map[string][]string{
+	"title": {
+		"//*[@id=\"itemTitle\"]",
+		"//h1[contains(@class, \"x-item-title__mainTitle\")]",
+		"//h1",
+	},
+	"seller": {
+		"//*[@id=\"RightSummaryPanel\"]//*[contains(@class, \"mbg-nw\")]",
+		"//*[contains(@class, \"x-sellercard-atf__info__about-seller\")]//a",
+	},
+	"sellerFeedback": {
+		"//*[@id=\"si-fb\"]",
+		"//*[contains(@class, \"x-sellercard-atf__data-item\")][contains(., \"feedback\")]",
+	},
+	"condition": {
+		"//*[@id=\"vi-itm-cond\"]",
+		"//*[contains(@class, \"x-item-condition-text\")]//*[contains(@class, \"ux-textspans\")]",
+	},
+	"quantityAvailable": {
+		"//*[@id=\"qtySubTxt\"]",
+		"//*[@id=\"qtyAvailability\"]//*[contains(., \"available\")]",
+	},
+	"quantitySold": {
+		"//*[contains(@class, \"vi-qtyS-hot-red\")]",
+		"//*[@id=\"qtyAvailability\"]//*[contains(., \"sold\")]",
+	},
+	"itemLocation": {
+		"//*[@itemprop=\"availableAtOrFrom\"]",
+		"//*[contains(@class, \"ux-labels-values--itemLocation\")]//*[contains(@class, \"ux-labels-values__values\")]",
+	},
+	"returns": {
+		"//*[@id=\"vi-ret-accrd-txt\"]",
+		"//*[contains(@class, \"ux-labels-values--returns\")]//*[contains(@class, \"ux-labels-values__values\")]",
+	},
+}
+
+// quantityExpr is the first integer of a quantity text, exp: More than 10 available, 1,234 sold
+var quantityExpr = regexp.MustCompile(`\d[\d,]*`)
+
+// parseQuantity get the quantity of text
+func parseQuantity(text string) *int {
+	s := quantityExpr.FindString(text)
+	if s == "" {
+		return nil
+	}
+	quantity, err := strconv.Atoi(strings.ReplaceAll(s, ",", ""))
+	if err != nil {
+		return nil
+	}
+	return &quantity
+}
+
+// extractAttributes get every attribute by the first xpath found, a missing attribute is left empty
+func extractAttributes(f finder, xpaths map[string][]string) *Attributes {
+	attributes := &Attributes{}
+	fields := map[string]*string{
+		"title":          &attributes.Title,
+		"seller":         &attributes.Seller,
+		"sellerFeedback": &attributes.SellerFeedback,
+		"condition":      &attributes.Condition,
+		"itemLocation":   &attributes.ItemLocation,
+		"returns":        &attributes.Returns,
+	}
+	find := func(name string) string {
+		text, _, err := findFirstText(f, xpaths[name])
+		if err != nil {
+			log.Printf("attribute %s not found", name)
+			return ""
+		}
+		return strings.Join(strings.Fields(text), " ")
+	}
+	for name, field := range fields {
+		*field = find(name)
+	}
+	attributes.QuantityAvailable = parseQuantity(find("quantityAvailable"))
+	attributes.QuantitySold = parseQuantity(find("quantitySold"))
+	return attributes
+}
@@ -0,0 +1,182 @@
+package capture
+
+import (
+	"github.com/tebeka/selenium"
+	"golang.org/x/net/html"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"y-clouds.com/tarantula/dom"
+)
+
+// PageRule
+// @Description: The heuristics of a kind of page, a page matches if any of the fragments is found, case-insensitive
+type PageRule struct {
+	TitleContains []string
+	TextContains  []string
+	UrlContains   []string
+	// Scope is the xpaths of the elements whose text is matched by TextContains, the whole page if empty,
+	// so that the same words in a hidden element or a variation option are not matched
+	Scope []string `ini:",,allowshadow" delim:"\n"`
+}
+
+// BlockRule
+// @Description: The heuristics of bot-challenge, captcha and block pages of a site
+type BlockRule struct {
+	PageRule `ini:",extends"`
+	// Cooldown is how long to back off the site after it is blocked, 0 means never
+	Cooldown time.Duration
+}
+
+// DefaultBlockRules is used when a site is not configured
+var DefaultBlockRules = map[string]BlockRule{
+	"ebay": {PageRule: PageRule{
+		TitleContains: []string{"Pardon Our Interruption", "Security Measure", "Access Denied"},
+		TextContains:  []string{"Please verify yourself to continue", "checking your browser", "To continue, please verify"},
+		UrlContains:   []string{"/splashui/captcha", "/splashui/challenge", "signin.ebay"},
+	}},
+	"amazon": {PageRule: PageRule{
+		TitleContains: []string{"Robot Check", "Sorry! Something went wrong"},
+		TextContains:  []string{"Enter the characters you see below", "Type the characters you see in this image", "not a robot"},
+		UrlContains:   []string{"/errors/validateCaptcha"},
+	}},
+}
+
+func containsFold(s string, fragments []string) (string, bool) {
+	lower := strings.ToLower(s)
+	for _, fragment := range fragments {
+		if fragment != "" && strings.Contains(lower, strings.ToLower(fragment)) {
+			return fragment, true
+		}
+	}
+	return "", false
+}
+
+// Match check the page against the rule
+//  @param url the current url
+//  @param title the page title
+//  @param text the rendered text of page
+//  @return string the reason, exp: title contains "Pardon Our Interruption"
+func (r PageRule) Match(url string, title string, text string) (string, bool) {
+	if fragment, ok := containsFold(title, r.TitleContains); ok {
+		return "title contains \"" + fragment + "\"", true
+	}
+	if fragment, ok := containsFold(url, r.UrlContains); ok {
+		return "url contains \"" + fragment + "\"", true
+	}
+	if fragment, ok := containsFold(text, r.TextContains); ok {
+		return "text contains \"" + fragment + "\"", true
+	}
+	return "", false
+}
+
+// pageState is the url, title and rendered text of the current page
+type pageState struct {
+	url   string
+	title string
+	text  string
+	// root is the parsed page source, nil if the source is not parsed
+	root *html.Node
+}
+
+// currentPageState get the state of the current page of WebDriver, the text is rendered from the source
+func currentPageState(wd selenium.WebDriver, source string) pageState {
+	url, _ := wd.CurrentURL()
+	title, _ := wd.Title()
+	page := pageState{url: url, title: title}
+	if root, err := dom.Parse(source); err == nil {
+		page.root, page.text = root, dom.Text(root)
+	}
+	return page
+}
+
+// scopeText is the rendered text of the elements matched by the xpaths, one element per line
+func (page pageState) scopeText(xpaths []string) string {
+	if page.root == nil {
+		return ""
+	}
+	var texts []string
+	for _, xpath := range xpaths {
+		nodes, err := dom.FindAll(page.root, xpath)
+		if err != nil {
+			log.Printf("Scope xpath %s.error: %v", xpath, err)
+			continue
+		}
+		for _, n := range nodes {
+			texts = append(texts, dom.Text(n))
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// matchPage check the page state against the rule, the text is the text of scope if the rule has one
+func (r PageRule) matchPage(page pageState) (string, bool) {
+	text := page.text
+	if len(r.Scope) > 0 {
+		text = page.scopeText(r.Scope)
+	}
+	return r.Match(page.url, page.title, text)
+}
+
+// BlockTracker count the blocks per site, and back off a blocked site for its cooldown
+type BlockTracker struct {
+	mu            sync.Mutex
+	counts        map[string]int
+	coolingUntil  map[string]time.Time
+	consecutively map[string]int
+}
+
+// NewBlockTracker make an empty tracker
+func NewBlockTracker() *BlockTracker {
+	return &BlockTracker{
+		counts:        map[string]int{},
+		coolingUntil:  map[string]time.Time{},
+		consecutively: map[string]int{},
+	}
+}
+
+// Blocked record a block of the site, the site cools down for the cooldown multiplied by the consecutive blocks,
+// at most 8 times, exp: 10m, 20m, 30m ... 80m
+//  @return int the total blocks of the site
+func (t *BlockTracker) Blocked(site string, cooldown time.Duration) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.counts[site]++
+	t.consecutively[site]++
+	if cooldown > 0 {
+		factor := t.consecutively[site]
+		if factor > 8 {
+			factor = 8
+		}
+		t.coolingUntil[site] = time.Now().Add(cooldown * time.Duration(factor))
+	}
+	log.Printf("Site %s is blocked, total: %d, consecutively: %d, cooling until: %v", site, t.counts[site],
+		t.consecutively[site], t.coolingUntil[site].Format(time.RFC3339))
+	return t.counts[site]
+}
+
+// Passed record a capture of the site not blocked, the consecutive count is reset
+func (t *BlockTracker) Passed(site string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.consecutively[site] = 0
+}
+
+// Count is the total blocks of the site
+func (t *BlockTracker) Count(site string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.counts[site]
+}
+
+// CoolingDown is the remaining cooldown of the site, 0 if the site can be visited
+func (t *BlockTracker) CoolingDown(site string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if remaining := time.Until(t.coolingUntil[site]); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
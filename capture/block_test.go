@@ -0,0 +1,60 @@
+package capture
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPageRuleMatch(t *testing.T) {
+	rule := DefaultBlockRules["ebay"].PageRule
+	tests := []struct {
+		url, title, text string
+		blocked          bool
+	}{
+		{"https://www.ebay.com/itm/1", "Pardon Our Interruption...", "", true},
+		{"https://www.ebay.com/splashui/captcha?ap=1", "eBay", "", true},
+		{"https://www.ebay.com/itm/1", "eBay", "Please VERIFY yourself to continue", true},
+		{"https://www.ebay.com/itm/1", "Apple iPhone | eBay", "US $499.99", false},
+	}
+	for _, tt := range tests {
+		if reason, blocked := rule.Match(tt.url, tt.title, tt.text); blocked != tt.blocked {
+			t.Errorf("Match(%s, %s, %s) = %v (%s), want %v", tt.url, tt.title, tt.text, blocked, reason, tt.blocked)
+		}
+	}
+}
+
+func TestBlockTrackerCooldown(t *testing.T) {
+	tracker := NewBlockTracker()
+	if wait := tracker.CoolingDown("ebay"); wait != 0 {
+		t.Errorf("CoolingDown() of a new site = %v, want 0", wait)
+	}
+
+	// the cooldown grows linearly with the consecutive blocks, at most 8 times
+	for i := 1; i <= 10; i++ {
+		if count := tracker.Blocked("ebay", time.Minute); count != i {
+			t.Errorf("Blocked() = %d, want %d", count, i)
+		}
+		factor := i
+		if factor > 8 {
+			factor = 8
+		}
+		wait := tracker.CoolingDown("ebay")
+		if want := time.Duration(factor) * time.Minute; wait > want || wait < want-time.Second {
+			t.Errorf("CoolingDown() after %d blocks = %v, want %v", i, wait, want)
+		}
+	}
+
+	tracker.Passed("ebay")
+	tracker.Blocked("ebay", time.Minute)
+	if wait := tracker.CoolingDown("ebay"); wait > time.Minute {
+		t.Errorf("CoolingDown() after a pass = %v, want <= 1m", wait)
+	}
+	if count := tracker.Count("ebay"); count != 11 {
+		t.Errorf("Count() = %d, want 11", count)
+	}
+
+	tracker.Blocked("amazon", 0)
+	if wait := tracker.CoolingDown("amazon"); wait != 0 {
+		t.Errorf("CoolingDown() without cooldown = %v, want 0", wait)
+	}
+}
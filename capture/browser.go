@@ -0,0 +1,183 @@
+package capture
+
+import (
+	"fmt"
+	"github.com/tebeka/selenium"
+	"github.com/tebeka/selenium/firefox"
+	"log"
+	"os"
+	"time"
+)
+
+// firefoxOptions is the firefox capabilities with the environment variables of firefox process
+type firefoxOptions struct {
+	firefox.Capabilities
+	Env map[string]string `json:"env,omitempty"`
+}
+
+// open start a Selenium WebDriver server instance, and connect to it
+//  @return selenium.WebDriver the browser session
+//  @return func() quit the session and stop the server
+func (s Selenium) open() (selenium.WebDriver, func(), error) {
+	// Start a Selenium WebDriver server instance (if one is not already running).
+	var (
+		geckoDriverPath = s.DriverPath
+		port            = s.Port
+	)
+	opts := []selenium.ServiceOption{
+		//selenium.StartFrameBuffer(),           // Start an X frame buffer for the browser to run in.x
+		selenium.GeckoDriver(geckoDriverPath), // Specify the path to GeckoDriver in order to use Firefox.
+		selenium.Output(os.Stderr),            // Output debug information to STDERR.
+	}
+	firefoxCaps := firefoxOptions{
+		Capabilities: firefox.Capabilities{
+			Args: append([]string{
+				"--headless",
+				"--no-sandbox",
+				"--disable-gpu",
+				"--disable-impl-side-painting",
+				"--disable-gpu-sandbox",
+				"--disable-accelerated-2d-canvas",
+				"--disable-accelerated-jpeg-decoding",
+				"--test-type=ui",
+			}, s.Profile.args()...),
+			Prefs: s.Profile.firefoxPrefs(),
+		},
+		Env: s.Profile.env(),
+	}
+	if s.Proxy != nil {
+		for name, value := range s.Proxy.firefoxPrefs() {
+			firefoxCaps.Prefs[name] = value
+		}
+	}
+	selenium.SetDebug(false)
+	service, err := selenium.NewGeckoDriverService(geckoDriverPath, port, opts[0])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Connect to the WebDriver instance running locally.
+	caps := selenium.Capabilities{"browserName": "firefox"}
+	caps[firefox.CapabilitiesKey] = firefoxCaps
+	wd, err := selenium.NewRemote(caps, fmt.Sprintf("http://localhost:%d", port))
+	// wd, err := selenium.NewRemote(caps, "")
+	if err != nil {
+		_ = service.Stop()
+		return nil, nil, err
+	}
+
+	// the window size is also applied by WebDriver, the args are ignored by some firefox versions
+	if s.Profile.Width > 0 && s.Profile.Height > 0 {
+		if err = wd.ResizeWindow("", s.Profile.Width, s.Profile.Height); err != nil {
+			log.Println("web.resize_window:", err)
+		}
+	}
+
+	stop := func() {
+		_ = wd.Quit()
+		_ = service.Stop()
+	}
+	return wd, stop, nil
+}
+
+// browserInfo get the browser name, version and user agent of WebDriver
+func browserInfo(wd selenium.WebDriver) (string, string) {
+	var browser, userAgent string
+	if caps, err := wd.Capabilities(); err == nil {
+		browser = fmt.Sprintf("%v %v", caps["browserName"], caps["browserVersion"])
+	}
+	if ua, err := wd.ExecuteScript("return navigator.userAgent", nil); err == nil {
+		userAgent, _ = ua.(string)
+	}
+	return browser, userAgent
+}
+
+// Site
+// @Description: The rules of a site, applied by every capturer when the page is opened
+type Site struct {
+	// BlockRule detect the block page
+	BlockRule BlockRule
+	// ListingRule detect the ended, removed and out-of-stock listing
+	ListingRule ListingRule
+	// OverlayRule dismiss the consent banners and popups covering the page
+	OverlayRule OverlayRule
+	// LocationRule set the delivery location of request
+	LocationRule LocationRule
+}
+
+// isListingState indicates the status is a state of listing, not a failure
+func isListingState(status string) bool {
+	switch ScreenshotsStatus(status) {
+	case LISTING_ENDED, NOT_FOUND, OUT_OF_STOCK:
+		return true
+	}
+	return false
+}
+
+// refreshPageSource keep the current page source, so that the evidence is the DOM captured,
+// not the page opened before the location, variation and steps are applied
+func refreshPageSource(wd selenium.WebDriver, result *Capture) {
+	if source, err := wd.PageSource(); err == nil {
+		result.PageSource = source
+	} else {
+		log.Println("web.page_source:", err)
+	}
+}
+
+// openPage navigate to the url of capture, then apply the site rules: keep the page source,
+// detect the block page, dismiss the overlays and detect the listing state
+//  @return bool whether to continue the capture, the status of result is set if not
+func (site Site) openPage(wd selenium.WebDriver, result *Capture) bool {
+	result.StartedAt = time.Now()
+	if err := wd.Get(result.Url); err != nil {
+		log.Println("web.open:", err)
+		result.Status = string(PAGE_ERROR)
+		return false
+	}
+	hookConsole(wd)
+
+	// Keep the page source as the evidence of what is parsed
+	refreshPageSource(wd, result)
+
+	// A bot-challenge or captcha page has no price, it is not a PRICE_ERROR
+	page := currentPageState(wd, result.PageSource)
+	if reason, blocked := site.BlockRule.matchPage(page); blocked {
+		log.Println("web.blocked:", reason)
+		result.Status = string(BLOCKED)
+		result.Reason = reason
+		return false
+	}
+
+	dismissOverlays(wd, site.OverlayRule)
+
+	// An ended or removed listing is not a tool failure, the viewport screenshot is kept as the evidence
+	if status, reason, ok := site.ListingRule.matchListing(page); ok {
+		listingState(wd, result, status, reason)
+		return false
+	}
+	return true
+}
+
+// checkStock detect the out-of-stock listing, after the variation is selected and the steps are executed
+//  @return bool whether to continue the capture, the status of result is set if not
+func (site Site) checkStock(wd selenium.WebDriver, result *Capture) bool {
+	refreshPageSource(wd, result)
+	if reason, ok := site.ListingRule.OutOfStock.matchPage(currentPageState(wd, result.PageSource)); ok {
+		listingState(wd, result, OUT_OF_STOCK, reason)
+		return false
+	}
+	return true
+}
+
+// listingState set the state of listing, the viewport screenshot is kept as the evidence
+func listingState(wd selenium.WebDriver, result *Capture, status ScreenshotsStatus, reason string) {
+	log.Printf("web.listing_state: %s, %s", status, reason)
+	var err error
+	if result.Screenshot, err = wd.Screenshot(); err != nil {
+		log.Println("web.listing_screenshot:", err)
+	}
+	refreshPageSource(wd, result)
+	result.CapturedAt = time.Now()
+	result.Status = string(status)
+	result.Reason = reason
+}
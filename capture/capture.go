@@ -1,9 +1,18 @@
 package capture
 
+import (
+	"strings"
+	"time"
+)
+
 // Selenium is the selenium attr
 type Selenium struct {
 	DriverPath string
 	Port       int
+	// Proxy is the proxy of browser selected for the request, nil to connect directly
+	Proxy *Proxy `ini:"-"`
+	// Profile is the browser fingerprint selected for the site and country
+	Profile Profile `ini:"-"`
 }
 
 type ScreenshotsStatus string
@@ -15,6 +24,20 @@ const (
 	SCREENSHOT_ERROR    ScreenshotsStatus = "SCREENSHOT_ERROR"
 	PRICE_ERROR         ScreenshotsStatus = "PRICE_ERROR"
 	UPLOAD_TO_OSS_ERROR ScreenshotsStatus = "UPLOAD_TO_OSS_ERROR"
+	// BLOCKED is a bot-challenge, captcha or block page served instead of the item
+	BLOCKED ScreenshotsStatus = "BLOCKED"
+	// LISTING_ENDED is a listing ended or removed by the seller
+	LISTING_ENDED ScreenshotsStatus = "LISTING_ENDED"
+	// NOT_FOUND is an item not found page, or a redirection to search results
+	NOT_FOUND ScreenshotsStatus = "NOT_FOUND"
+	// OUT_OF_STOCK is a listing without quantity available
+	OUT_OF_STOCK ScreenshotsStatus = "OUT_OF_STOCK"
+	// LOCATION_ERROR is a delivery location of request not applied
+	LOCATION_ERROR ScreenshotsStatus = "LOCATION_ERROR"
+	// VARIATION_ERROR is a variation of request not found or not available
+	VARIATION_ERROR ScreenshotsStatus = "VARIATION_ERROR"
+	// STEP_ERROR is an interaction step failed before capture
+	STEP_ERROR ScreenshotsStatus = "STEP_ERROR"
 )
 
 // ScreenshotsParam
@@ -25,6 +48,47 @@ type ScreenshotsParam struct {
 	Asin    string `json:"asin"`
 	Price   string `json:"price"`
 	PriceNo string `json:"priceNo"`
+	// PostalCode is the delivery location applied before the price and shipping are read, optional
+	PostalCode string `json:"postalCode,omitempty"`
+	// ExpectedSpecifics is the item specifics the listing should have, exp: {"Brand": "Acme", "EAN": "4006381333931"}, optional
+	ExpectedSpecifics map[string]string `json:"expectedSpecifics,omitempty"`
+	// Variation is the option by attribute name selected before the price and screenshot, exp: {"Size": "M"}, optional
+	Variation map[string]string `json:"variation,omitempty"`
+	// EnumerateVariations indicates whether to read the price of every variation
+	EnumerateVariations bool `json:"enumerateVariations,omitempty"`
+	// Type is item (default), seller or search
+	Type string `json:"type,omitempty"`
+	// Seller is the user name of seller, if the type is seller
+	Seller string `json:"seller,omitempty"`
+	// Query is the search keyword, if the type is search
+	Query string `json:"query,omitempty"`
+	// MaxPages is the max number of result pages captured, if the type is seller or search
+	MaxPages int `json:"maxPages,omitempty"`
+	// Enqueue indicates whether to enqueue an item capture message for every new item found in the result pages
+	Enqueue bool `json:"enqueue,omitempty"`
+	// Url is the page of generic channel
+	Url string `json:"url,omitempty"`
+	// Selectors is the regions captured of generic channel, css selectors or xpaths, the whole page if empty
+	Selectors []string `json:"selectors,omitempty"`
+	// PriceSelector is the element the price is read from of generic channel, css selector or xpath, optional
+	PriceSelector string `json:"priceSelector,omitempty"`
+	// Captions is the caption of every region of generic channel, drawn if the layout has captions
+	Captions []string `json:"captions,omitempty"`
+	// Steps is the interactions executed before extraction and screenshots, after the steps of site definition
+	Steps []Step `json:"steps,omitempty"`
+}
+
+// IsList indicates the request is a seller's item list or search results
+func (p ScreenshotsParam) IsList() bool {
+	return p.Type == CAPTURE_SELLER || p.Type == CAPTURE_SEARCH
+}
+
+// ListName is the name of a seller's item list or search results, used in place of asin, exp: seller-xxx
+func (p ScreenshotsParam) ListName() string {
+	if p.Type == CAPTURE_SELLER {
+		return CAPTURE_SELLER + "-" + p.Seller
+	}
+	return CAPTURE_SEARCH + "-" + strings.Join(strings.Fields(p.Query), "-")
 }
 
 // ScreenshotsResult
@@ -38,6 +102,148 @@ type ScreenshotsResult struct {
 	Status     string  `json:"status"`
 	Screenshot string  `json:"screenshot"`
 	NewPrice   float32 `json:"newPrice"`
+	// Sha256 is the hex SHA-256 of the png screenshot before the watermark, identical screenshots have the same hash
+	Sha256 string `json:"sha256,omitempty"`
+	// Thumbnails is the object key of every thumbnail of the screenshot, the key is the width, exp: {"320": "xxx-w320.png"}
+	Thumbnails map[string]string `json:"thumbnails,omitempty"`
+	// Deduplicated indicates the screenshot is identical to a previous one, and its key is reused
+	Deduplicated bool `json:"deduplicated"`
+	// PHash is the perceptual hash of the screenshot
+	PHash string `json:"phash,omitempty"`
+	// PreviousScreenshot is the screenshot of the last capture of the item
+	PreviousScreenshot string `json:"previousScreenshot,omitempty"`
+	// VisualChange is the visual difference score with the last capture, in [0, 1]
+	VisualChange float64 `json:"visualChange"`
+	// VisualChanged indicates the visual change is beyond the threshold
+	VisualChanged bool `json:"visualChanged"`
+	// DiffScreenshot is the picture highlighting the changed regions with the last capture
+	DiffScreenshot string `json:"diffScreenshot,omitempty"`
+	// DiffRegions is the number of changed regions with the last capture
+	DiffRegions int `json:"diffRegions,omitempty"`
+	// Manifest is the signed manifest of the capture, the signature is the object {manifest}.sig
+	Manifest string `json:"manifest,omitempty"`
+	// Html is the gzip compressed page source of the capture
+	Html string `json:"html,omitempty"`
+	// FrameHtml is the gzip compressed source of the main iframe, exp: the description of ebay item
+	FrameHtml string `json:"frameHtml,omitempty"`
+	// FailedSelector is the selector not found when the capture fails
+	FailedSelector string `json:"failedSelector,omitempty"`
+	// DebugArtifacts is the keys of the page state uploaded when the capture fails:
+	// screenshot, html and info (url, title, console logs)
+	DebugArtifacts map[string]string `json:"debugArtifacts,omitempty"`
+	// Reason is why the page is classified as the status, exp: BLOCKED, LISTING_ENDED
+	Reason string `json:"reason,omitempty"`
+	// Proxy is the proxy the page is opened through, selected by the country
+	Proxy string `json:"proxy,omitempty"`
+	// PostalCode is the delivery location applied to the page
+	PostalCode string `json:"postalCode,omitempty"`
+	// Shipping is the shipping text of the delivery location, exp: US $5.00 Standard Shipping
+	Shipping string `json:"shipping,omitempty"`
+	// Attributes is the item data read off the page besides price
+	Attributes *Attributes `json:"attributes,omitempty"`
+	// ItemSpecifics is the key/value pairs of item specifics section, exp: Brand, MPN, UPC
+	ItemSpecifics map[string]string `json:"itemSpecifics,omitempty"`
+	// SpecificsMismatches is the expected item specifics of request not matched, or missing in the listing
+	SpecificsMismatches []SpecificMismatch `json:"specificsMismatches,omitempty"`
+	// SpecificsMatched indicates all the expected item specifics are matched, nil if none is expected
+	SpecificsMatched *bool `json:"specificsMatched,omitempty"`
+	// Variation is the variation selected, the price and screenshot are of it
+	Variation map[string]string `json:"variation,omitempty"`
+	// Variations is the every variation of listing and its price, if enumerated
+	Variations []Variation `json:"variations,omitempty"`
+	Type       string      `json:"type,omitempty"`
+	Seller     string      `json:"seller,omitempty"`
+	Query      string      `json:"query,omitempty"`
+	// Pages is the result pages of a seller's item list or search results
+	Pages []ResultPage `json:"pages,omitempty"`
+	// Items is the items found in the result pages
+	Items []ListItem `json:"items,omitempty"`
+	// NewItems is the item ids not found in the last capture of the list
+	NewItems []string `json:"newItems,omitempty"`
+	// Enqueued is the number of item capture messages enqueued for the new items
+	Enqueued int `json:"enqueued,omitempty"`
+	// Fields is the fields of a site definition besides price, exp: title
+	Fields map[string]string `json:"fields,omitempty"`
+	// Steps is how the interaction steps are executed
+	Steps []StepResult `json:"steps,omitempty"`
+}
+
+// ResultPage
+// @Description: The screenshot of a result page
+type ResultPage struct {
+	Page       int    `json:"page"`
+	Url        string `json:"url"`
+	Screenshot string `json:"screenshot"`
+	// Items is the number of items in the page
+	Items int `json:"items"`
+}
+
+// Capture
+// @Description: What is taken from the web page
+type Capture struct {
+	// Url is the url of web page
+	Url string
+	// Price is the price in web page
+	Price float32
+	// Screenshot is the picture of web page
+	Screenshot []byte
+	// Status is the status of tarantula
+	Status string
+	// PageSource is the raw html of web page
+	PageSource string
+	// FrameSource is the raw html of the main iframe of web page, exp: the description of ebay item
+	FrameSource string
+	// Browser is the browser name and version
+	Browser string
+	// UserAgent is the user agent of browser
+	UserAgent string
+	// StartedAt is the time of opening the web page
+	StartedAt time.Time
+	// CapturedAt is the time of the screenshot taken
+	CapturedAt time.Time
+	// FailedSelector is the selector not found when the capture fails
+	FailedSelector string
+	// Reason is why the page is classified as the status, exp: BLOCKED, LISTING_ENDED
+	Reason string
+	// Debug is the state of web page when the capture fails
+	Debug *Debug
+	// PostalCode is the delivery location applied to the page
+	PostalCode string
+	// Shipping is the shipping text of the delivery location
+	Shipping string
+	// Attributes is the item data read off the page besides price
+	Attributes *Attributes
+	// ItemSpecifics is the key/value pairs of item specifics section
+	ItemSpecifics map[string]string
+	// Variation is the variation selected
+	Variation map[string]string
+	// Variations is the every variation of listing and its price
+	Variations []Variation
+	// Pages is the result pages of a seller's item list or search results
+	Pages []ListPage
+	// Fields is the fields of a site definition besides price
+	Fields map[string]string
+	// Steps is how the interaction steps are executed
+	Steps []StepResult
+}
+
+// Snapshot
+// @Description: The record uploaded next to the page source, what was extracted from it
+type Snapshot struct {
+	Url        string    `json:"url"`
+	Channel    string    `json:"channel"`
+	Country    string    `json:"country"`
+	Asin       string    `json:"asin"`
+	PostalCode string    `json:"postalCode,omitempty"`
+	Price      float32   `json:"price"`
+	Status     string    `json:"status"`
+	CapturedAt time.Time `json:"capturedAt"`
+	Screenshot string    `json:"screenshot"`
+	Html       string    `json:"html"`
+	FrameHtml  string    `json:"frameHtml,omitempty"`
+	// Attributes and ItemSpecifics are the values extracted, compared by the reextract command
+	Attributes    *Attributes       `json:"attributes,omitempty"`
+	ItemSpecifics map[string]string `json:"itemSpecifics,omitempty"`
 }
 
 type Screenshots interface {
@@ -45,5 +251,5 @@ type Screenshots interface {
 	Url() string
 
 	// WebScreenshots is used to capture web pictures
-	WebScreenshots() []byte
+	WebScreenshots() *Capture
 }
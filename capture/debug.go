@@ -0,0 +1,104 @@
+package capture
+
+import (
+	"fmt"
+	"github.com/tebeka/selenium"
+	"log"
+)
+
+// Debug
+// @Description: The state of web page when a capture fails
+type Debug struct {
+	// Screenshot is the picture of the current viewport
+	Screenshot []byte
+	// PageSource is the raw html of the current page
+	PageSource string
+	// Url is the current url, may differ from the requested url after redirection
+	Url string
+	// Title is the title of the current page
+	Title string
+	// ConsoleLogs is the console errors and warnings, uncaught errors and failed resources after the page is opened,
+	// the messages logged while the page is loading are missed
+	ConsoleLogs []string
+}
+
+// maxConsoleLogs is the most console messages kept by the page
+const maxConsoleLogs = 100
+
+// consoleHookScript keep the console errors and warnings, uncaught errors and failed resources in the page,
+// geckodriver does not support the log endpoint of WebDriver
+var consoleHookScript = fmt.Sprintf(`
+if (window.__tarantulaConsole) { return; }
+var logs = window.__tarantulaConsole = [];
+function keep(level, message) {
+	if (logs.length < %d) { logs.push(new Date().toISOString().substr(11, 12) + " [" + level + "] " + message); }
+}
+function text(args) {
+	return Array.prototype.map.call(args, function (a) {
+		if (a instanceof Error) { return a.stack || String(a); }
+		if (typeof a === "object") { try { return JSON.stringify(a); } catch (e) {} }
+		return String(a);
+	}).join(" ");
+}
+["error", "warn"].forEach(function (level) {
+	var original = console[level];
+	console[level] = function () {
+		keep(level.toUpperCase(), text(arguments));
+		return original.apply(console, arguments);
+	};
+});
+window.addEventListener("error", function (e) {
+	if (e.target && e.target !== window) {
+		keep("RESOURCE", "failed to load " + (e.target.src || e.target.href || e.target.tagName));
+	} else {
+		keep("UNCAUGHT", e.message + " at " + e.filename + ":" + e.lineno);
+	}
+}, true);
+window.addEventListener("unhandledrejection", function (e) { keep("UNHANDLED", text([e.reason])); });`, maxConsoleLogs)
+
+// readConsoleScript return the messages kept by consoleHookScript
+const readConsoleScript = `return window.__tarantulaConsole || [];`
+
+// hookConsole start keeping the console messages of the current page
+func hookConsole(wd selenium.WebDriver) {
+	if _, err := wd.ExecuteScript(consoleHookScript, nil); err != nil {
+		log.Println("debug.hook_console:", err)
+	}
+}
+
+// collectDebug take what is available of the current page, a failed item is skipped
+func collectDebug(wd selenium.WebDriver) *Debug {
+	debug := new(Debug)
+	var err error
+	if debug.Screenshot, err = wd.Screenshot(); err != nil {
+		log.Println("debug.screenshot:", err)
+	}
+	if debug.PageSource, err = wd.PageSource(); err != nil {
+		log.Println("debug.page_source:", err)
+	}
+	if debug.Url, err = wd.CurrentURL(); err != nil {
+		log.Println("debug.current_url:", err)
+	}
+	if debug.Title, err = wd.Title(); err != nil {
+		log.Println("debug.title:", err)
+	}
+
+	messages, err := wd.ExecuteScript(readConsoleScript, nil)
+	if err != nil {
+		log.Println("debug.console_logs:", err)
+	}
+	debug.ConsoleLogs = consoleLogs(messages)
+	return debug
+}
+
+// consoleLogs convert the messages returned by readConsoleScript
+func consoleLogs(messages interface{}) []string {
+	list, _ := messages.([]interface{})
+	var logs []string
+	for _, m := range list {
+		if s, ok := m.(string); ok {
+			logs = append(logs, s)
+		}
+	}
+	return logs
+}
@@ -0,0 +1,22 @@
+package capture
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConsoleLogs(t *testing.T) {
+	messages := []interface{}{"08:00:00.000 [ERROR] boom", 1, "08:00:01.000 [UNCAUGHT] x is not defined at a.js:1"}
+	want := []string{"08:00:00.000 [ERROR] boom", "08:00:01.000 [UNCAUGHT] x is not defined at a.js:1"}
+	if got := consoleLogs(messages); !reflect.DeepEqual(got, want) {
+		t.Errorf("consoleLogs() = %v, want %v", got, want)
+	}
+	// the page is not hooked or the script failed
+	if got := consoleLogs(nil); got != nil {
+		t.Errorf("consoleLogs(nil) = %v, want nil", got)
+	}
+	if !strings.Contains(consoleHookScript, "if (logs.length < 100)") {
+		t.Errorf("consoleHookScript does not keep at most %d messages", maxConsoleLogs)
+	}
+}
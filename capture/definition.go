@@ -0,0 +1,199 @@
+package capture
+
+import (
+	"fmt"
+	"gopkg.in/ini.v1"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SiteDefinition
+// @Description: A marketplace described by a file instead of code, captured by the generic capturer
+type SiteDefinition struct {
+	// Name is the channel of the site, default: the file name without extension
+	Name string
+	// Urls is the url template of item by lower-case country, the "default" one is used by other countries,
+	// placeholders: {asin} {country}
+	Urls map[string]string
+	// PriceSelectors is the selectors of price, tried in order
+	PriceSelectors []string
+	// Fields is the selectors of every field besides price, tried in order
+	Fields map[string][]string
+	// Regions is the selectors of regions captured, spliced in order
+	Regions []string
+	// Captions is the caption of every region
+	Captions []string
+	// Layout is how the regions are spliced, nil to use the layout of config
+	Layout *Layout
+	// Steps is the interactions executed before extraction and screenshots
+	Steps []Step
+	// Site is the block, listing, overlay and location rules of the site
+	Site Site
+	// RuleSections is the rule sections of the file, exp: Block, Listing.Ended, only they override the default rules
+	RuleSections []string
+}
+
+// listingStates is the sections of listing rules, exp: [Listing.Ended]
+var listingStates = []string{"NotFound", "Ended", "OutOfStock"}
+
+// LoadSiteDefinitions load the site definitions of the *.ini files in the directory, exp: sites/walmart.ini
+//  Name = walmart
+//  [Url]
+//  US = https://www.walmart.com/ip/{asin}
+//  [Price]
+//  Selector = [itemprop="price"]
+//  [Fields]
+//  title = h1
+//  [Regions]
+//  Selector = #main-content
+//  Caption = Item
+//  [Layout]
+//  Direction = column
+//  [Steps]
+//  Step = click? #see-more
+//  [Block] / [Listing.{NotFound|Ended|OutOfStock}] / [Overlay] / [Location]
+// a key is repeated for every selector, a selector is a css selector or an xpath starting with /
+//  @return map[string]*SiteDefinition the definitions by lower-case name
+func LoadSiteDefinitions(dir string) (map[string]*SiteDefinition, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.ini"))
+	if err != nil {
+		return nil, err
+	}
+	definitions := map[string]*SiteDefinition{}
+	for _, file := range files {
+		definition, err := LoadSiteDefinition(file)
+		if err != nil {
+			return nil, err
+		}
+		definitions[strings.ToLower(definition.Name)] = definition
+	}
+	return definitions, nil
+}
+
+// LoadSiteDefinition load a site definition file
+func LoadSiteDefinition(file string) (*SiteDefinition, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	// a key is repeated for every selector, and # is a css selector instead of an inline comment, exp: #price
+	cfg, err := ini.LoadSources(ini.LoadOptions{AllowShadows: true, IgnoreInlineComment: true}, content)
+	if err != nil {
+		return nil, fmt.Errorf("site definition %s: %v", file, err)
+	}
+
+	definition := &SiteDefinition{
+		Name:   cfg.Section("").Key("Name").MustString(strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))),
+		Urls:   map[string]string{},
+		Fields: map[string][]string{},
+	}
+	for _, key := range cfg.Section("Url").Keys() {
+		definition.Urls[strings.ToLower(key.Name())] = key.String()
+	}
+	if len(definition.Urls) == 0 {
+		return nil, fmt.Errorf("site definition %s: no [Url]", file)
+	}
+	definition.PriceSelectors = shadowValues(cfg.Section("Price"), "Selector")
+	definition.Regions = shadowValues(cfg.Section("Regions"), "Selector")
+	definition.Captions = shadowValues(cfg.Section("Regions"), "Caption")
+	if cfg.HasSection("Layout") {
+		definition.Layout = new(Layout)
+		err = cfg.Section("Layout").MapTo(definition.Layout)
+		if err == nil {
+			err = definition.Layout.Validate()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("site definition %s [Layout]: %v", file, err)
+		}
+	}
+	for _, line := range shadowValues(cfg.Section("Steps"), "Step") {
+		step, err := ParseStep(line)
+		if err != nil {
+			return nil, fmt.Errorf("site definition %s: %v", file, err)
+		}
+		definition.Steps = append(definition.Steps, step)
+	}
+	for _, key := range cfg.Section("Fields").Keys() {
+		definition.Fields[key.Name()] = key.ValueWithShadows()
+	}
+
+	rules := map[string]interface{}{
+		"Block":    &definition.Site.BlockRule,
+		"Overlay":  &definition.Site.OverlayRule,
+		"Location": &definition.Site.LocationRule,
+	}
+	states := []*PageRule{&definition.Site.ListingRule.NotFound, &definition.Site.ListingRule.Ended, &definition.Site.ListingRule.OutOfStock}
+	for i, state := range listingStates {
+		rules["Listing."+state] = states[i]
+	}
+	for name, rule := range rules {
+		if !cfg.HasSection(name) {
+			continue
+		}
+		if err = cfg.Section(name).MapTo(rule); err != nil {
+			return nil, fmt.Errorf("site definition %s [%s]: %v", file, name, err)
+		}
+		definition.RuleSections = append(definition.RuleSections, name)
+	}
+	sort.Strings(definition.RuleSections)
+	return definition, nil
+}
+
+// hasRule whether the file has the rule section
+func (d *SiteDefinition) hasRule(name string) bool {
+	for _, section := range d.RuleSections {
+		if section == name {
+			return true
+		}
+	}
+	return false
+}
+
+// OverrideDefaultRules replace the default rules of the site by the rule sections of the definition,
+// the rules without section are kept, exp: a definition of ebay with [Block] only keeps the listing rules of ebay
+func (d *SiteDefinition) OverrideDefaultRules() {
+	name := strings.ToLower(d.Name)
+	if d.hasRule("Block") {
+		DefaultBlockRules[name] = d.Site.BlockRule
+	}
+	if d.hasRule("Overlay") {
+		DefaultOverlayRules[name] = d.Site.OverlayRule
+	}
+	if d.hasRule("Location") {
+		DefaultLocationRules[name] = d.Site.LocationRule
+	}
+	listing := DefaultListingRules[name]
+	states := []*PageRule{&listing.NotFound, &listing.Ended, &listing.OutOfStock}
+	defined := []PageRule{d.Site.ListingRule.NotFound, d.Site.ListingRule.Ended, d.Site.ListingRule.OutOfStock}
+	changed := false
+	for i, state := range listingStates {
+		if d.hasRule("Listing." + state) {
+			*states[i] = defined[i]
+			changed = true
+		}
+	}
+	if changed {
+		DefaultListingRules[name] = listing
+	}
+}
+
+// shadowValues get the values of the repeated key
+func shadowValues(section *ini.Section, name string) []string {
+	if !section.HasKey(name) {
+		return nil
+	}
+	return section.Key(name).ValueWithShadows()
+}
+
+// PageUrl make the url of item in the country
+func (d *SiteDefinition) PageUrl(country string, asin string) (string, error) {
+	template, ok := d.Urls[strings.ToLower(country)]
+	if !ok {
+		if template, ok = d.Urls["default"]; !ok {
+			return "", fmt.Errorf("site %s has no url of country %s", d.Name, country)
+		}
+	}
+	return strings.NewReplacer("{asin}", asin, "{country}", country).Replace(template), nil
+}
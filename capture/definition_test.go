@@ -0,0 +1,86 @@
+package capture
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestOverrideDefaultRules(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "ebay.ini")
+	content := "[Url]\ndefault = https://www.ebay.com/itm/{asin}\n[Listing.Ended]\nTextContains = auction closed\n"
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	definition, err := LoadSiteDefinition(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(definition.RuleSections, []string{"Listing.Ended"}) {
+		t.Errorf("RuleSections = %v, want [Listing.Ended]", definition.RuleSections)
+	}
+
+	block, listing := DefaultBlockRules["ebay"], DefaultListingRules["ebay"]
+	overlay, location := DefaultOverlayRules["ebay"], DefaultLocationRules["ebay"]
+	defer func() {
+		DefaultBlockRules["ebay"], DefaultListingRules["ebay"] = block, listing
+		DefaultOverlayRules["ebay"], DefaultLocationRules["ebay"] = overlay, location
+	}()
+	definition.OverrideDefaultRules()
+
+	// the rules without section are kept
+	if !reflect.DeepEqual(DefaultBlockRules["ebay"], block) || !reflect.DeepEqual(DefaultOverlayRules["ebay"], overlay) ||
+		!reflect.DeepEqual(DefaultLocationRules["ebay"], location) {
+		t.Errorf("the block, overlay and location rules of ebay are overridden without their sections")
+	}
+	got := DefaultListingRules["ebay"]
+	if !reflect.DeepEqual(got.NotFound, listing.NotFound) || !reflect.DeepEqual(got.OutOfStock, listing.OutOfStock) {
+		t.Errorf("the listing states of ebay are overridden without their sections")
+	}
+	if !reflect.DeepEqual(got.Ended.TextContains, []string{"auction closed"}) {
+		t.Errorf("Ended.TextContains = %v, want [auction closed]", got.Ended.TextContains)
+	}
+}
+
+func TestLoadSiteDefinitionIdSelectors(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "shop.ini")
+	content := `[Url]
+default = https://shop.example.com/p/{asin}#reviews
+[Price]
+Selector = #price .amount
+Selector = //*[@id="price"]
+[Fields]
+title = #title; h1
+[Steps]
+Step = click? #show-more
+Step = type(5s) #zip => 10001
+[Overlay]
+Click = //a[@href="#close"]
+# a line starting with # is still a comment
+; and so is a line starting with ;
+`
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	definition, err := LoadSiteDefinition(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := definition.Urls["default"]; got != "https://shop.example.com/p/{asin}#reviews" {
+		t.Errorf("Urls[default] = %q, want the fragment kept", got)
+	}
+	if want := []string{"#price .amount", `//*[@id="price"]`}; !reflect.DeepEqual(definition.PriceSelectors, want) {
+		t.Errorf("PriceSelectors = %q, want %q", definition.PriceSelectors, want)
+	}
+	if want := []string{"#title; h1"}; !reflect.DeepEqual(definition.Fields["title"], want) {
+		t.Errorf("Fields[title] = %q, want %q", definition.Fields["title"], want)
+	}
+	if len(definition.Steps) != 2 || definition.Steps[0].Selector != "#show-more" || definition.Steps[1].Selector != "#zip" ||
+		definition.Steps[1].Text != "10001" {
+		t.Errorf("Steps = %+v, want the #id selectors", definition.Steps)
+	}
+	if want := []string{`//a[@href="#close"]`}; !reflect.DeepEqual(definition.Site.OverlayRule.Click, want) {
+		t.Errorf("Overlay.Click = %q, want %q", definition.Site.OverlayRule.Click, want)
+	}
+}
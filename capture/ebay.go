@@ -1,14 +1,13 @@
 package capture
 
 import (
-	"errors"
 	"fmt"
 	"github.com/tebeka/selenium"
-	"github.com/tebeka/selenium/firefox"
 	"log"
-	"os"
 	"regexp"
 	"strconv"
+	"strings"
+	"time"
 	"y-clouds.com/tarantula/tools"
 )
 
@@ -18,37 +17,70 @@ const (
 	EBAY_DETAIL_ELE_ID         = "CenterPanelInternal"
 	EBAY_DESRIPTION_ELE_ID     = "vi-desc-maincntr"
 	EBAY_DESRIPTION_WRAPPER_ID = "desc_wrapper_ctr"
+	EBAY_DESRIPTION_FRAME_ID   = "desc_ifr"
 )
 
 // Ebay is the ebay params
 type Ebay struct {
-	Asin       string
-	DriverPath string
-	Port       int
+	Asin string
+	Selenium
+	Site
+	// DescriptionSource indicates whether to keep the source of the description iframe
+	DescriptionSource bool
+	// Debug indicates whether to collect the state of web page when the capture fails
+	Debug bool
+	// PostalCode is the delivery location applied before the price is read, empty to keep the default one
+	PostalCode string
+	// Variation is the option by attribute name selected before the price is read
+	Variation map[string]string
+	// EnumerateVariations indicates whether to read the price of every variation
+	EnumerateVariations bool
+	// Steps is the interactions executed before extraction and screenshots
+	Steps []Step
+	// Layout is how the detail and description are spliced
+	Layout Layout
 }
 
 // Url
 //  @Description: Make url of ebay
 //  @receiver ebay
 //  @return string
-func (ebay Ebay) url() string {
+func (ebay Ebay) Url() string {
 	return fmt.Sprintf(EBAY_URL_PREFIX, ebay.Asin)
 }
 
-// getPrice is a regular expression to get the price
+// priceExpr is the first number of price text, the groups are separated by comma, dot, apostrophe or space,
+// exp: US $1,299.99, 12,99 €, 1.299,00 EUR, CHF 1'299.50, 15 000 ₽, $15
+var priceExpr = regexp.MustCompile(`\d+(?:[.,'\x{a0}\x{202f}]\d+|\s\d{3}\b)*`)
+
+// getPriceExpr get the price by the first number of text, the last comma or dot is the decimal separator,
+// unless it is followed by 3 digits, which is a thousands separator, exp: 1,299 is 1299, 12,99 is 12.99
+//  @return error if the text has no number
 func getPriceExpr(text string) (float32, error) {
-	reg := regexp.MustCompile(`\d+\.\d+`)
-	s := reg.FindAllString(text, -1)
-	fmt.Println("FindAllString", s)
-	if len(s) > 0 {
-		fmt.Println("price text: ", s[0])
-		price, err := strconv.ParseFloat(s[0], 32)
-		if err != nil {
-			return 0.0, err
+	number := priceExpr.FindString(text)
+	if number == "" {
+		return 0.0, fmt.Errorf("price not found in %q", text)
+	}
+	log.Println("price text: ", number)
+
+	integer, fraction := number, ""
+	if i := strings.LastIndexAny(number, ".,"); i >= 0 && len(number)-i-1 != 3 {
+		integer, fraction = number[:i], number[i+1:]
+	}
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
 		}
-		return float32(price), nil
+		return -1
+	}, integer)
+	if fraction != "" {
+		digits += "." + fraction
 	}
-	return 0.0, nil
+	price, err := strconv.ParseFloat(digits, 32)
+	if err != nil {
+		return 0.0, fmt.Errorf("price %q: %v", text, err)
+	}
+	return float32(price), nil
 }
 
 // reSizeBrowserWindow Resize the window, or return the original WebDriver
@@ -105,92 +137,112 @@ func getDescriptionCutSize(wd selenium.WebDriver, eleId string, bottomId string)
 	return size.Width, size.Height - bottomSize.Height, nil
 }
 
+// EBAY_PRICE_XPATHS is the xpath of price panel, tried in order
+var EBAY_PRICE_XPATHS = []string{
+	"//*[@id=\"prcIsum\"]",
+	"//*[@id=\"mainContent\"]/form/div[2]/div/div[1]/div/div[2]/div[1]/span[1]",
+	"//*[@id=\"mainContent\"]/form/div[2]/div/div[1]/div[1]/div/div[2]/div/span[1]/span",
+}
+
+// EBAY_SHIPPING_XPATHS is the xpath of shipping panel, tried in order
+var EBAY_SHIPPING_XPATHS = []string{
+	"//*[@id=\"fshippingCost\"]/..",
+	"//*[contains(@class, \"ux-labels-values--shipping\")]//*[contains(@class, \"ux-labels-values__values\")]",
+}
+
+// extractPrice Get the price by the first price xpath found
+//  @return float32 the price
+//  @return string the xpath of price element
+func extractPrice(f finder) (float32, string, error) {
+	priceText, xpath, err := findFirstText(f, EBAY_PRICE_XPATHS)
+	if err != nil {
+		return 0.0, "", err
+	}
+	log.Println("price text: ", priceText)
+
+	price, err := getPriceExpr(priceText)
+	if err != nil {
+		log.Println("Get element text expr, price.error:", err)
+		return 0.0, xpath, err
+	}
+	return price, xpath, nil
+}
+
 // getPrice Get the price by element id
 func getPrice(wd selenium.WebDriver) (float32, error) {
-	// Get price panel
-	ebayPriceXpaths := []string{
-		"//*[@id=\"prcIsum\"]",
-		"//*[@id=\"mainContent\"]/form/div[2]/div/div[1]/div/div[2]/div[1]/span[1]",
-		"//*[@id=\"mainContent\"]/form/div[2]/div/div[1]/div[1]/div/div[2]/div/span[1]/span",
-	}
+	price, _, err := extractPrice(webFinder{wd})
+	return price, err
+}
 
-	for _, xpath := range ebayPriceXpaths {
-		elem, err := wd.FindElement(selenium.ByXPATH, xpath)
-		if err != nil {
-			log.Printf("Price XPath:%s find element error ,price.error:%v \n", xpath, err)
-		} else {
-			priceText, err := elem.Text()
-			if err != nil {
-				log.Println("Get element text, price.error:", err)
-				return 0.0, err
-			}
-
-			fmt.Println("price text: ", priceText)
-
-			price, err := getPriceExpr(priceText)
-			if err != nil {
-				log.Println("Get element text expr, price.error:", err)
-				return 0.0, err
-			}
-			return price, nil
-		}
+// frameSource get the source of the iframe, then switch back to the top page
+func frameSource(wd selenium.WebDriver, frameId string) (string, error) {
+	frame, err := wd.FindElement(selenium.ByID, frameId)
+	if err != nil {
+		return "", err
+	}
+	if err = wd.SwitchFrame(frame); err != nil {
+		return "", err
 	}
-	return 0.0, errors.New("the element id can not find the price element")
+	defer func() {
+		if err := wd.SwitchFrame(nil); err != nil {
+			log.Println("web.switch_top_frame:", err)
+		}
+	}()
+
+	return wd.PageSource()
 }
 
 // WebScreenshots
-//  @return float32  the price in web page
-//  @return []byte 	the tarantula of web page
-//  @return string the status of tarantula
-func (ebay Ebay) WebScreenshots() (float32, []byte, string) {
-	// Start a Selenium WebDriver server instance (if one is not already running).
-	var (
-		geckoDriverPath = ebay.DriverPath
-		port            = ebay.Port
-	)
-	opts := []selenium.ServiceOption{
-		//selenium.StartFrameBuffer(),           // Start an X frame buffer for the browser to run in.x
-		selenium.GeckoDriver(geckoDriverPath), // Specify the path to GeckoDriver in order to use Firefox.
-		selenium.Output(os.Stderr),            // Output debug information to STDERR.
-	}
-	firefoxCaps := firefox.Capabilities{
-		Args: []string{
-			"--headless",
-			"--start-maximized",
-			//"--window-size=1200x600",
-			"--no-sandbox",
-			"--user-agent=Mozilla/5.0 (Macintosh; Intel Mac OS X 10_14_1) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/70.0.3538.77 Safari/537.36",
-			"--disable-gpu",
-			"--disable-impl-side-painting",
-			"--disable-gpu-sandbox",
-			"--disable-accelerated-2d-canvas",
-			"--disable-accelerated-jpeg-decoding",
-			"--test-type=ui",
-		},
-	}
-	selenium.SetDebug(false)
-	fmt.Println("NewGeckoDriverService:")
-	fmt.Println(geckoDriverPath)
-	service, err := selenium.NewGeckoDriverService(geckoDriverPath, port, opts[0])
+//  @return *Capture the price, the tarantula and the status of web page,
+//  with the page source and browser information as the evidence
+func (ebay Ebay) WebScreenshots() *Capture {
+	result := &Capture{Url: ebay.Url(), Status: string(SCREENSHOT_ERROR)}
+
+	wd, stop, err := ebay.Selenium.open()
 	if err != nil {
-		panic(err) // panic is used only as an example and is not otherwise recommended.
+		log.Println("web.selenium_open:", err)
+		result.Status = string(PAGE_ERROR)
+		return result
 	}
-	defer service.Stop()
+	defer stop()
+	result.Browser, result.UserAgent = browserInfo(wd)
+	defer func() {
+		// run before quit
+		if ebay.Debug && result.Status != string(SUCCESS) && !isListingState(result.Status) {
+			result.Debug = collectDebug(wd)
+		}
+	}()
 
-	// Connect to the WebDriver instance running locally.
-	caps := selenium.Capabilities{"browserName": "firefox"}
-	caps.AddFirefox(firefoxCaps)
-	wd, err := selenium.NewRemote(caps, fmt.Sprintf("http://localhost:%d", port))
-	// wd, err := selenium.NewRemote(caps, "")
-	if err != nil {
-		panic(err)
+	if !ebay.Site.openPage(wd, result) {
+		if isListingState(result.Status) {
+			result.Price, _ = getPrice(wd)
+		}
+		return result
+	}
+
+	if ebay.PostalCode != "" && !ebay.Site.setLocation(wd, ebay.PostalCode, result) {
+		return result
 	}
-	defer wd.Quit()
 
-	// Navigate to the simple playground interface.
-	if err := wd.Get(ebay.url()); err != nil {
-		log.Println("web.open:", err)
-		return 0.0, nil, string(PAGE_ERROR)
+	if (len(ebay.Variation) > 0 || ebay.EnumerateVariations) &&
+		!applyVariations(wd, EBAY_VARIATION_XPATH, ebay.Variation, ebay.EnumerateVariations, getPrice, result) {
+		return result
+	}
+
+	if !runSteps(wd, ebay.Steps, result) {
+		return result
+	}
+
+	if !ebay.Site.checkStock(wd, result) {
+		result.Price, _ = getPrice(wd)
+		return result
+	}
+
+	if ebay.DescriptionSource {
+		result.FrameSource, err = frameSource(wd, EBAY_DESRIPTION_FRAME_ID)
+		if err != nil {
+			log.Println("web.description_source:", err)
+		}
 	}
 
 	// Resize window
@@ -198,25 +250,37 @@ func (ebay Ebay) WebScreenshots() (float32, []byte, string) {
 
 	// Get price
 	price, err := getPrice(wd)
+	result.Price = price
 	if err != nil {
 		log.Printf("Find price element error: %v \n", err)
-		return price, nil, string(PRICE_ERROR)
+		result.Status = string(PRICE_ERROR)
+		result.FailedSelector = strings.Join(EBAY_PRICE_XPATHS, " | ")
+		return result
 	}
+	if shipping, _, err := findFirstText(webFinder{wd}, EBAY_SHIPPING_XPATHS); err == nil {
+		result.Shipping = strings.Join(strings.Fields(shipping), " ")
+	}
+	result.Attributes = extractAttributes(webFinder{wd}, EBAY_ATTRIBUTE_XPATHS)
+	result.ItemSpecifics = extractSpecifics(webFinder{wd}, EBAY_SPECIFICS_LAYOUTS)
 
 	// Screenshot
 	// cut two image to one
 	detailImgBytes, err := elementScreenshots(wd, EBAY_DETAIL_ELE_ID)
 	if err != nil || len(detailImgBytes) == 0 {
 		log.Printf("Cant find element by.ID: %s \n", EBAY_DETAIL_ELE_ID)
-		return price, nil, string(SCREENSHOT_ERROR)
+		result.FailedSelector = fmt.Sprintf("//*[@id=\"%s\"]", EBAY_DETAIL_ELE_ID)
+		return result
 	}
 	fmt.Println("len(detailImgBytes): ", len(detailImgBytes))
 
 	descriptionImgBytes, err := elementScreenshots(wd, EBAY_DESRIPTION_ELE_ID)
 	if err != nil || len(descriptionImgBytes) == 0 {
 		log.Printf("Cant find element by.ID: %s \n", EBAY_DESRIPTION_ELE_ID)
-		return price, nil, string(SCREENSHOT_ERROR)
+		result.FailedSelector = fmt.Sprintf("//*[@id=\"%s\"]", EBAY_DESRIPTION_ELE_ID)
+		return result
 	}
+	result.CapturedAt = time.Now()
+	refreshPageSource(wd, result)
 
 	// cut picture
 	width, height, err := getDescriptionCutSize(wd, EBAY_DESRIPTION_ELE_ID, EBAY_DESRIPTION_WRAPPER_ID)
@@ -229,14 +293,14 @@ func (ebay Ebay) WebScreenshots() (float32, []byte, string) {
 
 	if len(detailImgBytes) > 0 && len(descriptionImgBytes) > 0 {
 		// splice
-		screenshotBytes, err := tools.SplicePicsBytes(detailImgBytes, descriptionImgBytes, true, "png")
+		screenshotBytes, err := ebay.Layout.splice([][]byte{detailImgBytes, descriptionImgBytes}, []string{"Item", "Description"})
 		if err != nil {
 			log.Println("screenshot.error: ", err)
-			return price, nil, string(SCREENSHOT_ERROR)
+			return result
 		}
-		return price, screenshotBytes, string(SUCCESS)
+		result.Screenshot = screenshotBytes
+		result.Status = string(SUCCESS)
 	}
 
-	return price, nil, string(SCREENSHOT_ERROR)
-
+	return result
 }
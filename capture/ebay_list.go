@@ -0,0 +1,203 @@
+package capture
+
+import (
+	"fmt"
+	"github.com/tebeka/selenium"
+	"log"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// EBAY_SELLER_URL is the item list of a seller, by user name
+	EBAY_SELLER_URL = "https://www.ebay.com/sch/i.html?_ssn=%s&_ipg=%d&_pgn=%d"
+	// EBAY_SEARCH_URL is the search results of a keyword
+	EBAY_SEARCH_URL = "https://www.ebay.com/sch/i.html?_nkw=%s&_ipg=%d&_pgn=%d"
+	// EBAY_RESULTS_XPATH is the result list of a page, the screenshot of page is taken of it
+	EBAY_RESULTS_XPATH = "//ul[contains(@class, \"srp-results\")]"
+	// EBAY_RESULT_XPATH is the items of result list
+	EBAY_RESULT_XPATH = "//ul[contains(@class, \"srp-results\")]/li[contains(@class, \"s-item\")]"
+)
+
+// EBAY_RESULT_COLUMNS is the link, title and price of a result item, relative to the item
+var EBAY_RESULT_COLUMNS = []string{
+	".//a[contains(@class, \"s-item__link\")]/@href",
+	".//*[contains(@class, \"s-item__title\")]",
+	".//*[contains(@class, \"s-item__price\")]",
+}
+
+// ebayItemIdExpr is the item id in the link of item, exp: https://www.ebay.com/itm/123456789012?hash=...
+var ebayItemIdExpr = regexp.MustCompile(`/itm/(?:[^/?]+/)?(\d+)`)
+
+// capture types of request
+const (
+	// CAPTURE_ITEM is a single item, the default type
+	CAPTURE_ITEM = "item"
+	// CAPTURE_SELLER is the item list of a seller's storefront
+	CAPTURE_SELLER = "seller"
+	// CAPTURE_SEARCH is the search results of a keyword
+	CAPTURE_SEARCH = "search"
+)
+
+// defaultMaxPages is the pages captured of a list, if the request has no maxPages
+const defaultMaxPages = 5
+
+// ListItem
+// @Description: An item of a seller's item list or search results
+type ListItem struct {
+	ItemId string  `json:"itemId"`
+	Title  string  `json:"title"`
+	Price  float32 `json:"price"`
+	Url    string  `json:"url"`
+	// Page is the page the item is found in
+	Page int `json:"page"`
+}
+
+// ListPage
+// @Description: A result page of a seller's item list or search results
+type ListPage struct {
+	Page       int
+	Url        string
+	Screenshot []byte
+	Items      []ListItem
+}
+
+// EbayList is the params of a seller's item list or search results
+type EbayList struct {
+	// Type is CAPTURE_SELLER or CAPTURE_SEARCH
+	Type string
+	// Seller is the user name of seller
+	Seller string
+	// Query is the search keyword
+	Query string
+	// MaxPages is the max number of pages paginated through
+	MaxPages int
+	// PageSize is the number of items per page
+	PageSize int
+	Selenium
+	Site
+	// Debug indicates whether to collect the state of web page when the capture fails
+	Debug bool
+}
+
+// PageUrl make the url of the result page
+func (list EbayList) PageUrl(page int) string {
+	pageSize := list.PageSize
+	if pageSize <= 0 {
+		pageSize = 240
+	}
+	if list.Type == CAPTURE_SELLER {
+		return fmt.Sprintf(EBAY_SELLER_URL, url.QueryEscape(list.Seller), pageSize, page)
+	}
+	return fmt.Sprintf(EBAY_SEARCH_URL, url.QueryEscape(list.Query), pageSize, page)
+}
+
+// Url
+//  @Description: Make url of the first result page
+func (list EbayList) Url() string {
+	return list.PageUrl(1)
+}
+
+// extractListItems get the items of result page, the placeholder item "Shop on eBay" is skipped
+func extractListItems(f finder, page int) []ListItem {
+	rows, err := f.FindRows(EBAY_RESULT_XPATH, EBAY_RESULT_COLUMNS...)
+	if err != nil {
+		log.Println("web.list_items:", err)
+		return nil
+	}
+	items := make([]ListItem, 0, len(rows))
+	for _, row := range rows {
+		match := ebayItemIdExpr.FindStringSubmatch(row[0])
+		title := strings.Join(strings.Fields(row[1]), " ")
+		if match == nil || strings.EqualFold(title, "Shop on eBay") {
+			continue
+		}
+		// the price of a variation listing is a range, exp: $10.00 to $20.00, the lowest is kept
+		price, _ := getPriceExpr(row[2])
+		link := row[0]
+		if i := strings.Index(link, "?"); i >= 0 {
+			link = link[:i]
+		}
+		items = append(items, ListItem{ItemId: match[1], Title: title, Price: price, Url: link, Page: page})
+	}
+	return items
+}
+
+// pageScreenshot take the screenshot of result list, or the viewport if the list is not found
+func pageScreenshot(wd selenium.WebDriver) ([]byte, error) {
+	if elem, err := wd.FindElement(selenium.ByXPATH, EBAY_RESULTS_XPATH); err == nil {
+		if screenshot, err := elem.Screenshot(true); err == nil && len(screenshot) > 0 {
+			return screenshot, nil
+		}
+	}
+	return wd.Screenshot()
+}
+
+// WebScreenshots paginate through the result pages, until a page has no new item or MaxPages
+//  @return *Capture the pages with their screenshots and items, the status is SUCCESS if a page is captured
+func (list EbayList) WebScreenshots() *Capture {
+	result := &Capture{Url: list.Url(), Status: string(SCREENSHOT_ERROR)}
+
+	wd, stop, err := list.Selenium.open()
+	if err != nil {
+		log.Println("web.selenium_open:", err)
+		result.Status = string(PAGE_ERROR)
+		return result
+	}
+	defer stop()
+	result.Browser, result.UserAgent = browserInfo(wd)
+	defer func() {
+		if list.Debug && result.Status != string(SUCCESS) {
+			result.Debug = collectDebug(wd)
+		}
+	}()
+
+	// a result page is not a listing, the listing rule would take it as NOT_FOUND
+	site := list.Site
+	site.ListingRule = ListingRule{}
+
+	maxPages := list.MaxPages
+	if maxPages <= 0 {
+		maxPages = defaultMaxPages
+	}
+	seen := map[string]bool{}
+	for page := 1; page <= maxPages; page++ {
+		pageResult := &Capture{Url: list.PageUrl(page)}
+		if !site.openPage(wd, pageResult) {
+			// the pages captured are kept, if a later page fails
+			if len(result.Pages) == 0 {
+				result.Status, result.Reason, result.PageSource = pageResult.Status, pageResult.Reason, pageResult.PageSource
+			}
+			break
+		}
+		if page == 1 {
+			result.StartedAt, result.PageSource = pageResult.StartedAt, pageResult.PageSource
+		}
+
+		var items []ListItem
+		for _, item := range extractListItems(webFinder{wd}, page) {
+			if !seen[item.ItemId] {
+				seen[item.ItemId] = true
+				items = append(items, item)
+			}
+		}
+		// past the last page, eBay serves the last page again
+		if len(items) == 0 {
+			log.Printf("web.list_page %d has no new item", page)
+			break
+		}
+
+		screenshot, err := pageScreenshot(wd)
+		if err != nil {
+			log.Printf("web.list_screenshot %d: %v", page, err)
+			result.FailedSelector = EBAY_RESULTS_XPATH
+			break
+		}
+		result.Pages = append(result.Pages, ListPage{Page: page, Url: pageResult.Url, Screenshot: screenshot, Items: items})
+		result.CapturedAt = time.Now()
+		result.Status = string(SUCCESS)
+	}
+	return result
+}
@@ -0,0 +1,39 @@
+package capture
+
+import "testing"
+
+func TestGetPriceExpr(t *testing.T) {
+	tests := []struct {
+		text string
+		want float32
+	}{
+		{"US $499.99", 499.99},
+		{"US $1,299.99", 1299.99},
+		{"$15", 15},
+		{"$1,299", 1299},
+		{"12,99 €", 12.99},
+		{"1.299,00 EUR", 1299},
+		{"1.299.000 ₫", 1299000},
+		{"CHF 1'299.50", 1299.5},
+		{"15 000 ₽", 15000},
+		{"15 000,50 €", 15000.5},
+		{"$10.00 to $20.00", 10},
+		{"£7.5", 7.5},
+	}
+	for _, tt := range tests {
+		got, err := getPriceExpr(tt.text)
+		if err != nil {
+			t.Errorf("getPriceExpr(%q) error: %v", tt.text, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("getPriceExpr(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+
+	for _, text := range []string{"", "Price unavailable", "US $"} {
+		if _, err := getPriceExpr(text); err == nil {
+			t.Errorf("getPriceExpr(%q), want error", text)
+		}
+	}
+}
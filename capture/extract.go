@@ -0,0 +1,148 @@
+package capture
+
+import (
+	"fmt"
+	"github.com/tebeka/selenium"
+	"golang.org/x/net/html"
+	"log"
+	"strings"
+	"y-clouds.com/tarantula/dom"
+)
+
+// finder find the text of the first element matched by xpath,
+// so that the same extraction runs on the live page and on a stored html snapshot
+type finder interface {
+	FindText(xpath string) (string, error)
+	// FindRows find the elements of row xpath, and the text of the first element of every column xpath
+	// relative to the row, a missing column is empty. A column ending with /@name is the attribute of element
+	FindRows(row string, columns ...string) ([][]string, error)
+}
+
+// webFinder find elements on the live page of WebDriver
+type webFinder struct {
+	wd selenium.WebDriver
+}
+
+func (f webFinder) FindText(xpath string) (string, error) {
+	elem, err := f.wd.FindElement(selenium.ByXPATH, xpath)
+	if err != nil {
+		return "", err
+	}
+	return elem.Text()
+}
+
+// splitAttribute split the trailing attribute step of column xpath, exp: .//a/@href is .//a and href
+func splitAttribute(xpath string) (string, string) {
+	i := strings.LastIndex(xpath, "/@")
+	if i < 0 || strings.ContainsAny(xpath[i+2:], "[]/=()") {
+		return xpath, ""
+	}
+	return xpath[:i], xpath[i+2:]
+}
+
+func (f webFinder) FindRows(row string, columns ...string) ([][]string, error) {
+	elems, err := f.wd.FindElements(selenium.ByXPATH, row)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([][]string, 0, len(elems))
+	for _, elem := range elems {
+		texts := make([]string, len(columns))
+		for i, column := range columns {
+			path, attr := splitAttribute(column)
+			cell, err := elem.FindElement(selenium.ByXPATH, path)
+			if err != nil {
+				continue
+			}
+			if attr != "" {
+				texts[i], _ = cell.GetAttribute(attr)
+			} else {
+				texts[i], _ = cell.Text()
+			}
+		}
+		rows = append(rows, texts)
+	}
+	return rows, nil
+}
+
+// htmlFinder find elements on the parsed html snapshot
+type htmlFinder struct {
+	root *html.Node
+}
+
+func (f htmlFinder) FindText(xpath string) (string, error) {
+	n, err := dom.Find(f.root, xpath)
+	if err != nil {
+		return "", err
+	}
+	return dom.Text(n), nil
+}
+
+func (f htmlFinder) FindRows(row string, columns ...string) ([][]string, error) {
+	nodes, err := dom.FindAll(f.root, row)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([][]string, 0, len(nodes))
+	for _, n := range nodes {
+		texts := make([]string, len(columns))
+		for i, column := range columns {
+			path, attr := splitAttribute(column)
+			cell, err := dom.Find(n, path)
+			if err != nil {
+				continue
+			}
+			if attr != "" {
+				texts[i] = dom.Attr(cell, attr)
+			} else {
+				texts[i] = dom.Text(cell)
+			}
+		}
+		rows = append(rows, texts)
+	}
+	return rows, nil
+}
+
+// findFirstText try the xpaths in order, return the text of the first element found
+//  @return string the text of element
+//  @return string the xpath of element
+func findFirstText(f finder, xpaths []string) (string, string, error) {
+	for _, xpath := range xpaths {
+		text, err := f.FindText(xpath)
+		if err != nil {
+			log.Printf("XPath:%s find element error: %v \n", xpath, err)
+			continue
+		}
+		return text, xpath, nil
+	}
+	return "", "", fmt.Errorf("none of the xpaths can find the element: %s", strings.Join(xpaths, ", "))
+}
+
+// Extraction is the fields extracted from a web page
+type Extraction struct {
+	Price float32 `json:"price"`
+	// PriceXPath is the xpath of price element found
+	PriceXPath string `json:"priceXPath"`
+	// Attributes is the item data besides price
+	Attributes *Attributes `json:"attributes,omitempty"`
+	// ItemSpecifics is the key/value pairs of item specifics section
+	ItemSpecifics map[string]string `json:"itemSpecifics,omitempty"`
+}
+
+// ExtractFromHtml run the field extraction of the channel on the html snapshot, without a browser
+//  @param channel exp: ebay
+//  @param source the page source
+func ExtractFromHtml(channel string, source string) (*Extraction, error) {
+	root, err := dom.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	f := htmlFinder{root: root}
+	switch strings.ToLower(channel) {
+	case "ebay":
+		price, xpath, err := extractPrice(f)
+		return &Extraction{Price: price, PriceXPath: xpath, Attributes: extractAttributes(f, EBAY_ATTRIBUTE_XPATHS),
+			ItemSpecifics: extractSpecifics(f, EBAY_SPECIFICS_LAYOUTS)}, err
+	}
+	return nil, fmt.Errorf("unsupported channel: %s", channel)
+}
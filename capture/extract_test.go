@@ -0,0 +1,199 @@
+package capture
+
+import (
+	"github.com/tebeka/selenium"
+	"reflect"
+	"testing"
+	"y-clouds.com/tarantula/dom"
+)
+
+// siteXPaths is the xpaths of the site rules, the rules may use css selectors where a selector is accepted
+func siteXPaths(site Site) []string {
+	var xpaths []string
+	xpaths = append(xpaths, site.OverlayRule.Click...)
+	xpaths = append(xpaths, site.OverlayRule.Remove...)
+	xpaths = append(xpaths, site.LocationRule.Open...)
+	xpaths = append(xpaths, site.LocationRule.Input...)
+	xpaths = append(xpaths, site.LocationRule.Submit...)
+	for _, rule := range []PageRule{site.BlockRule.PageRule, site.ListingRule.NotFound, site.ListingRule.Ended, site.ListingRule.OutOfStock} {
+		xpaths = append(xpaths, rule.Scope...)
+	}
+	return xpaths
+}
+
+func TestEbayXPathsCompile(t *testing.T) {
+	xpaths := append([]string{EBAY_VARIATION_XPATH, EBAY_RESULTS_XPATH, EBAY_RESULT_XPATH}, EBAY_PRICE_XPATHS...)
+	xpaths = append(xpaths, EBAY_SHIPPING_XPATHS...)
+	for _, column := range EBAY_RESULT_COLUMNS {
+		path, _ := splitAttribute(column)
+		xpaths = append(xpaths, column, path)
+	}
+	for _, attribute := range EBAY_ATTRIBUTE_XPATHS {
+		xpaths = append(xpaths, attribute...)
+	}
+	for _, layout := range EBAY_SPECIFICS_LAYOUTS {
+		xpaths = append(xpaths, layout.Row, layout.Label, layout.Value)
+	}
+	xpaths = append(xpaths, siteXPaths(Site{BlockRule: DefaultBlockRules["ebay"], ListingRule: DefaultListingRules["ebay"],
+		OverlayRule: DefaultOverlayRules["ebay"], LocationRule: DefaultLocationRules["ebay"]})...)
+
+	root, _ := dom.Parse("<html></html>")
+	for _, xpath := range xpaths {
+		if _, err := dom.FindAll(root, xpath); err != nil {
+			t.Errorf("xpath %s: %v", xpath, err)
+		}
+	}
+}
+
+func TestSiteDefinitionSelectorsCompile(t *testing.T) {
+	definitions, err := LoadSiteDefinitions("../sites")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(definitions) == 0 {
+		t.Fatal("no site definition in ../sites")
+	}
+
+	root, _ := dom.Parse("<html></html>")
+	for name, definition := range definitions {
+		selectors := append([]string{}, definition.PriceSelectors...)
+		selectors = append(selectors, definition.Regions...)
+		for _, field := range definition.Fields {
+			selectors = append(selectors, field...)
+		}
+		for _, step := range definition.Steps {
+			selectors = append(selectors, step.Selector)
+		}
+		for _, selector := range selectors {
+			if selector == "" || selectorBy(selector) != selenium.ByXPATH {
+				continue
+			}
+			if _, err := dom.FindAll(root, selector); err != nil {
+				t.Errorf("%s selector %s: %v", name, selector, err)
+			}
+		}
+		for _, xpath := range siteXPaths(definition.Site) {
+			if _, err := dom.FindAll(root, xpath); err != nil {
+				t.Errorf("%s rule xpath %s: %v", name, xpath, err)
+			}
+		}
+	}
+}
+
+// the item page of the classic layout
+const ebayClassicPage = `<html><body>
+<div id="CenterPanelInternal">
+<h1 id="itemTitle"><span>Details about</span> Apple iPhone 12 64GB</h1>
+<div id="vi-itm-cond">New</div>
+<span id="qtySubTxt"><span>More than 10 available</span></span>
+<span class="vi-qtyS-hot-red"><a>1,234 sold</a></span>
+<span id="prcIsum" itemprop="price">US $499.99</span>
+<span id="fshippingCost"><span>$10.00</span></span> Standard Shipping
+<span itemprop="availableAtOrFrom">Shenzhen, China</span>
+<span id="vi-ret-accrd-txt">30 days returns</span>
+</div>
+<div id="RightSummaryPanel"><span class="mbg-nw">best_seller</span><span id="si-fb">99.5% Positive feedback</span></div>
+<div id="viTabs_0_is">
+<dl class="ux-labels-values"><dt>Brand:</dt><dd>Apple</dd></dl>
+<dl class="ux-labels-values"><dt>Color:</dt><dd> Black </dd></dl>
+</div>
+</body></html>`
+
+// the item page of the evo layout
+const ebayEvoPage = `<html><body>
+<div id="mainContent"><form><div></div><div><div><div><div><div></div><div><div>
+<span>US $25.00</span>
+</div></div></div></div></div></div></form></div>
+<h1 class="x-item-title__mainTitle"><span class="ux-textspans">Lego Set</span></h1>
+<div class="x-item-condition-text"><span class="ux-textspans">Used</span></div>
+<div class="ux-layout-section-evo">
+<div class="ux-labels-values"><div class="ux-labels-values__labels">Brand</div><div class="ux-labels-values__values">LEGO</div></div>
+<div class="ux-labels-values"><div class="ux-labels-values__labels">Theme</div><div class="ux-labels-values__values">City</div></div>
+</div>
+</body></html>`
+
+func TestExtractFromHtml(t *testing.T) {
+	extraction, err := ExtractFromHtml("ebay", ebayClassicPage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if extraction.Price != 499.99 || extraction.PriceXPath != EBAY_PRICE_XPATHS[0] {
+		t.Errorf("price = %v by %s, want 499.99 by %s", extraction.Price, extraction.PriceXPath, EBAY_PRICE_XPATHS[0])
+	}
+	attributes := extraction.Attributes
+	if attributes.Title != "Details about Apple iPhone 12 64GB" || attributes.Condition != "New" ||
+		attributes.Seller != "best_seller" || attributes.ItemLocation != "Shenzhen, China" || attributes.Returns != "30 days returns" {
+		t.Errorf("attributes = %+v", attributes)
+	}
+	if attributes.QuantityAvailable == nil || *attributes.QuantityAvailable != 10 {
+		t.Errorf("quantity available = %v, want 10", attributes.QuantityAvailable)
+	}
+	if attributes.QuantitySold == nil || *attributes.QuantitySold != 1234 {
+		t.Errorf("quantity sold = %v, want 1234", attributes.QuantitySold)
+	}
+	if want := map[string]string{"Brand": "Apple", "Color": "Black"}; !reflect.DeepEqual(extraction.ItemSpecifics, want) {
+		t.Errorf("specifics = %v, want %v", extraction.ItemSpecifics, want)
+	}
+
+	extraction, err = ExtractFromHtml("ebay", ebayEvoPage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if extraction.Price != 25 || extraction.PriceXPath != EBAY_PRICE_XPATHS[1] {
+		t.Errorf("price = %v by %s, want 25 by %s", extraction.Price, extraction.PriceXPath, EBAY_PRICE_XPATHS[1])
+	}
+	if extraction.Attributes.Title != "Lego Set" || extraction.Attributes.Condition != "Used" {
+		t.Errorf("attributes = %+v", extraction.Attributes)
+	}
+	if want := map[string]string{"Brand": "LEGO", "Theme": "City"}; !reflect.DeepEqual(extraction.ItemSpecifics, want) {
+		t.Errorf("specifics = %v, want %v", extraction.ItemSpecifics, want)
+	}
+}
+
+func TestExtractFromHtmlErrors(t *testing.T) {
+	if _, err := ExtractFromHtml("ebay", "<html><body>no price</body></html>"); err == nil {
+		t.Errorf("ExtractFromHtml() of a page without price, want error")
+	}
+	if _, err := ExtractFromHtml("unknown", ebayClassicPage); err == nil {
+		t.Errorf("ExtractFromHtml() of unknown channel, want error")
+	}
+}
+
+func TestExtractListItems(t *testing.T) {
+	root, err := dom.Parse(`<html><body><ul class="srp-results srp-list">
+<li class="s-item"><a class="s-item__link" href="https://www.ebay.com/itm/123456789012?hash=abc"></a>
+<h3 class="s-item__title">Shop on eBay</h3></li>
+<li class="s-item"><a class="s-item__link" href="https://www.ebay.com/itm/Lego-Set/223456789012?hash=abc"></a>
+<h3 class="s-item__title">Lego   Set</h3><span class="s-item__price">$10.00 to $20.00</span></li>
+<li class="s-item"><a class="s-item__link" href="https://www.ebay.com/itm/323456789012"></a>
+<h3 class="s-item__title">Phone</h3><span class="s-item__price">$1,299.50</span></li>
+</ul></body></html>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items := extractListItems(htmlFinder{root: root}, 2)
+	want := []ListItem{
+		{ItemId: "223456789012", Title: "Lego Set", Price: 10, Url: "https://www.ebay.com/itm/Lego-Set/223456789012", Page: 2},
+		{ItemId: "323456789012", Title: "Phone", Price: 1299.5, Url: "https://www.ebay.com/itm/323456789012", Page: 2},
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("extractListItems() = %+v, want %+v", items, want)
+	}
+}
+
+func TestSplitAttribute(t *testing.T) {
+	tests := []struct {
+		xpath, path, attr string
+	}{
+		{".//a/@href", ".//a", "href"},
+		{".//a", ".//a", ""},
+		{".//a[@href]", ".//a[@href]", ""},
+		{`.//a[@class="x"]/@data-id`, `.//a[@class="x"]`, "data-id"},
+	}
+	for _, tt := range tests {
+		if path, attr := splitAttribute(tt.xpath); path != tt.path || attr != tt.attr {
+			t.Errorf("splitAttribute(%s) = %s, %s, want %s, %s", tt.xpath, path, attr, tt.path, tt.attr)
+		}
+	}
+}
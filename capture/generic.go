@@ -0,0 +1,190 @@
+package capture
+
+import (
+	"fmt"
+	"github.com/tebeka/selenium"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// GENERIC_CHANNEL is the channel of an arbitrary page, the message carries the url and selectors
+const GENERIC_CHANNEL = "generic"
+
+// Generic is the params of an arbitrary page
+type Generic struct {
+	PageUrl string
+	// Selectors is the regions to capture, spliced vertically in order, the whole page is captured if empty
+	Selectors []string
+	// PriceSelectors is the elements the price is read from, tried in order, optional
+	PriceSelectors []string
+	// Fields is the selectors of every field besides price, tried in order, exp: title
+	Fields map[string][]string
+	// PostalCode is the delivery location applied before the price is read, empty to keep the default one
+	PostalCode string
+	// Steps is the interactions executed before extraction and screenshots
+	Steps []Step
+	// Captions is the caption of every region
+	Captions []string
+	// Layout is how the regions are spliced
+	Layout Layout
+	Selenium
+	Site
+	// Debug indicates whether to collect the state of web page when the capture fails
+	Debug bool
+}
+
+// Url
+//  @Description: The url of the message
+func (g Generic) Url() string {
+	return g.PageUrl
+}
+
+// CheckPageUrl check the url of a generic page is an absolute http or https url,
+// so that a message can not open a local file or a browser page, exp: file:///etc/passwd, about:config
+func CheckPageUrl(rawUrl string) error {
+	u, err := url.Parse(strings.TrimSpace(rawUrl))
+	if err != nil {
+		return fmt.Errorf("invalid url %q: %v", rawUrl, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid url %q: the scheme is not http or https", rawUrl)
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("invalid url %q: the host is empty", rawUrl)
+	}
+	return nil
+}
+
+// selectorBy is how WebDriver finds the selector, an xpath starts with / or (, otherwise a css selector
+func selectorBy(selector string) string {
+	if strings.HasPrefix(selector, "/") || strings.HasPrefix(selector, "(") {
+		return selenium.ByXPATH
+	}
+	return selenium.ByCSSSelector
+}
+
+// findSelector find the element by xpath or css selector
+func findSelector(wd selenium.WebDriver, selector string) (selenium.WebElement, error) {
+	return wd.FindElement(selectorBy(selector), selector)
+}
+
+// findFirstSelectorText try the selectors in order, return the text of the first element found
+//  @return string the text of element
+//  @return string the selector of element
+func findFirstSelectorText(wd selenium.WebDriver, selectors []string) (string, string, error) {
+	for _, selector := range selectors {
+		elem, err := findSelector(wd, selector)
+		if err != nil {
+			log.Printf("Selector:%s find element error: %v \n", selector, err)
+			continue
+		}
+		text, err := elem.Text()
+		if err != nil {
+			continue
+		}
+		return text, selector, nil
+	}
+	return "", "", fmt.Errorf("none of the selectors can find the element: %s", strings.Join(selectors, ", "))
+}
+
+// WebScreenshots
+//  @return *Capture the screenshot of the regions, and the price if the price selector is given
+func (g Generic) WebScreenshots() *Capture {
+	result := &Capture{Url: g.Url(), Status: string(SCREENSHOT_ERROR)}
+	if err := CheckPageUrl(g.PageUrl); err != nil {
+		log.Println("web.page_url:", err)
+		result.Status = string(PAGE_ERROR)
+		result.Reason = err.Error()
+		return result
+	}
+
+	wd, stop, err := g.Selenium.open()
+	if err != nil {
+		log.Println("web.selenium_open:", err)
+		result.Status = string(PAGE_ERROR)
+		return result
+	}
+	defer stop()
+	result.Browser, result.UserAgent = browserInfo(wd)
+	defer func() {
+		if g.Debug && result.Status != string(SUCCESS) && !isListingState(result.Status) {
+			result.Debug = collectDebug(wd)
+		}
+	}()
+
+	if !g.Site.openPage(wd, result) {
+		return result
+	}
+
+	if g.PostalCode != "" && !g.Site.setLocation(wd, g.PostalCode, result) {
+		return result
+	}
+
+	if !runSteps(wd, g.Steps, result) {
+		return result
+	}
+
+	if !g.Site.checkStock(wd, result) {
+		return result
+	}
+
+	if len(g.PriceSelectors) > 0 {
+		text, _, err := findFirstSelectorText(wd, g.PriceSelectors)
+		if err != nil {
+			log.Printf("Find price element error: %v \n", err)
+			result.Status = string(PRICE_ERROR)
+			result.FailedSelector = strings.Join(g.PriceSelectors, " | ")
+			return result
+		}
+		if result.Price, err = getPriceExpr(text); err != nil {
+			log.Println("Get element text expr, price.error:", err)
+			result.Status = string(PRICE_ERROR)
+			result.Reason = err.Error()
+			result.FailedSelector = strings.Join(g.PriceSelectors, " | ")
+			return result
+		}
+	}
+	for name, selectors := range g.Fields {
+		if text, _, err := findFirstSelectorText(wd, selectors); err == nil {
+			if result.Fields == nil {
+				result.Fields = map[string]string{}
+			}
+			result.Fields[name] = strings.Join(strings.Fields(text), " ")
+		}
+	}
+
+	// the body is the whole page
+	selectors, captions := g.Selectors, g.Captions
+	if len(selectors) == 0 {
+		selectors = []string{"body"}
+	}
+	if len(captions) == 0 {
+		captions = selectors
+	}
+	screenshots := make([][]byte, 0, len(selectors))
+	for _, selector := range selectors {
+		elem, err := findSelector(wd, selector)
+		var screenshot []byte
+		if err == nil {
+			screenshot, err = elem.Screenshot(true)
+		}
+		if err != nil || len(screenshot) == 0 {
+			log.Printf("Cant capture element %s: %v \n", selector, err)
+			result.FailedSelector = selector
+			return result
+		}
+		screenshots = append(screenshots, screenshot)
+	}
+	result.CapturedAt = time.Now()
+	refreshPageSource(wd, result)
+
+	result.Screenshot, err = g.Layout.splice(screenshots, captions)
+	if err != nil {
+		log.Println("screenshot.error: ", err)
+		return result
+	}
+	result.Status = string(SUCCESS)
+	return result
+}
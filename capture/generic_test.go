@@ -0,0 +1,25 @@
+package capture
+
+import "testing"
+
+func TestCheckPageUrl(t *testing.T) {
+	for _, rawUrl := range []string{"https://www.example.com/item/1?ref=a", "http://10.0.0.1:8080/", " HTTPS://example.com "} {
+		if err := CheckPageUrl(rawUrl); err != nil {
+			t.Errorf("CheckPageUrl(%q) error: %v", rawUrl, err)
+		}
+	}
+	for _, rawUrl := range []string{"", "file:///etc/passwd", "about:config", "javascript:alert(1)", "chrome://settings",
+		"ftp://example.com/a", "https:///path", "https://:443/", "www.example.com/item/1", "//example.com/a", "http://exa mple.com"} {
+		if err := CheckPageUrl(rawUrl); err == nil {
+			t.Errorf("CheckPageUrl(%q), want error", rawUrl)
+		}
+	}
+}
+
+func TestGenericInvalidUrl(t *testing.T) {
+	// the page is rejected before the browser is opened
+	result := Generic{PageUrl: "file:///etc/passwd"}.WebScreenshots()
+	if result.Status != string(PAGE_ERROR) || result.Reason == "" {
+		t.Errorf("WebScreenshots() of a file url = %s %q, want PAGE_ERROR with the reason", result.Status, result.Reason)
+	}
+}
@@ -0,0 +1,68 @@
+package capture
+
+import (
+	"fmt"
+	"y-clouds.com/tarantula/tools"
+)
+
+// Layout
+// @Description: How the captured regions are spliced into the screenshot
+type Layout struct {
+	// Direction is column, row or grid, default column
+	Direction string
+	// Columns is the number of columns of grid
+	Columns int
+	// Align is start, center or end, default start
+	Align string
+	// Padding is the space around and between the regions
+	Padding int
+	// Background is the color of space, exp: #ffffff, default white
+	Background string
+	// Captions indicates whether to draw the caption of every region above it
+	Captions bool
+	// MaxWidth and MaxHeight scale the screenshot down to fit, 0 is unlimited
+	MaxWidth  int
+	MaxHeight int
+}
+
+// options make the layout options of tools
+func (l Layout) options(captions []string) (tools.LayoutOptions, error) {
+	opt := tools.LayoutOptions{
+		Direction: l.Direction,
+		Columns:   l.Columns,
+		Align:     l.Align,
+		Padding:   l.Padding,
+		MaxWidth:  l.MaxWidth,
+		MaxHeight: l.MaxHeight,
+	}
+	if l.Background != "" {
+		background, err := tools.ParseHexColor(l.Background)
+		if err != nil {
+			return opt, fmt.Errorf("layout background: %v", err)
+		}
+		opt.Background = background
+	}
+	if l.Captions {
+		opt.Captions = captions
+	}
+	return opt, nil
+}
+
+// Validate check the layout when it is loaded, so that a typo fails the config instead of every capture
+func (l Layout) Validate() error {
+	opt, err := l.options(nil)
+	if err != nil {
+		return err
+	}
+	return opt.Validate()
+}
+
+// splice compose the screenshots of regions
+//  @param captions the caption of every region, drawn if the layout has captions
+func (l Layout) splice(screenshots [][]byte, captions []string) ([]byte, error) {
+	opt, err := l.options(captions)
+	if err != nil {
+		return nil, err
+	}
+	return tools.LayoutBytes(screenshots, opt, "png")
+}
@@ -0,0 +1,46 @@
+package capture
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLayoutValidate(t *testing.T) {
+	valid := []Layout{{}, {Direction: "grid", Columns: 2, Align: "center", Background: "#f0f0f0"}}
+	for _, layout := range valid {
+		if err := layout.Validate(); err != nil {
+			t.Errorf("Validate(%+v) = %v", layout, err)
+		}
+	}
+	invalid := []Layout{{Direction: "Column"}, {Align: "left"}, {Background: "white"}}
+	for _, layout := range invalid {
+		if err := layout.Validate(); err == nil {
+			t.Errorf("Validate(%+v), want error", layout)
+		}
+	}
+}
+
+func TestSiteDefinitionLayout(t *testing.T) {
+	definitions, err := LoadSiteDefinitions("../sites")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, definition := range definitions {
+		if definition.Layout != nil {
+			if err = definition.Layout.Validate(); err != nil {
+				t.Errorf("%s layout: %v", name, err)
+			}
+		}
+	}
+
+	file := filepath.Join(t.TempDir(), "shop.ini")
+	content := "[Url]\ndefault = https://shop.example/{asin}\n[Layout]\nDirection = diagonal\n"
+	if err = os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = LoadSiteDefinition(file); err == nil || !strings.Contains(err.Error(), "diagonal") {
+		t.Errorf("LoadSiteDefinition() of an invalid layout = %v, want error", err)
+	}
+}
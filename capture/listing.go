@@ -0,0 +1,57 @@
+package capture
+
+// ListingRule
+// @Description: The heuristics of the listing states of a site. NotFound and Ended are checked when the page is opened,
+// OutOfStock is checked after the variation is selected, because the stock is of the variation
+type ListingRule struct {
+	// NotFound is the "item not found" page, or a redirection to search results
+	NotFound PageRule
+	// Ended is the listing ended or removed by the seller
+	Ended PageRule
+	// OutOfStock is the listing without quantity available
+	OutOfStock PageRule
+}
+
+// DefaultListingRules is used when a site is not configured
+var DefaultListingRules = map[string]ListingRule{
+	"ebay": {
+		NotFound: PageRule{
+			TitleContains: []string{"Error Page", "Page not found"},
+			TextContains:  []string{"We looked everywhere.", "Looks like this page is missing", "The item you're looking for does not exist"},
+			UrlContains:   []string{"/sch/", "/b/", "/error"},
+		},
+		Ended: PageRule{
+			TextContains: []string{"This listing has ended", "This listing was ended by the seller", "Bidding has ended on this item",
+				"The seller has relisted this item or one like this", "This listing is no longer available"},
+		},
+		OutOfStock: PageRule{
+			TextContains: []string{"This item is out of stock", "Out of stock", "Currently sold out"},
+			// the quantity and availability of the item, the options of variations say "out of stock" too
+			Scope: []string{
+				"//*[@id=\"qtySubTxt\"]",
+				"//*[@id=\"qtyAvailability\"]",
+				"//*[contains(@class, \"x-quantity__availability\")]",
+				"//*[contains(@class, \"d-quantity__availability\")]",
+			},
+		},
+	},
+}
+
+// matchListing check the page against NotFound and Ended
+//  @return ScreenshotsStatus the state of listing
+//  @return string the reason
+func (r ListingRule) matchListing(page pageState) (ScreenshotsStatus, string, bool) {
+	rules := []struct {
+		status ScreenshotsStatus
+		rule   PageRule
+	}{
+		{NOT_FOUND, r.NotFound},
+		{LISTING_ENDED, r.Ended},
+	}
+	for _, state := range rules {
+		if reason, ok := state.rule.matchPage(page); ok {
+			return state.status, reason, true
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,61 @@
+package capture
+
+import (
+	"testing"
+	"y-clouds.com/tarantula/dom"
+)
+
+// testPageState parse the source as the current page
+func testPageState(t *testing.T, source string) pageState {
+	t.Helper()
+	root, err := dom.Parse(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pageState{url: "https://www.ebay.com/itm/1", title: "Item | eBay", text: dom.Text(root), root: root}
+}
+
+func TestOutOfStockScope(t *testing.T) {
+	rule := DefaultListingRules["ebay"].OutOfStock
+	tests := []struct {
+		name    string
+		source  string
+		matched bool
+	}{
+		{"variation option", `<html><body>
+<select id="msku-sel-1"><option>Red</option><option>Blue [out of stock]</option></select>
+<div class="x-quantity__availability"><span>5 available</span></div>
+</body></html>`, false},
+		{"hidden message", `<html><body><div style="display:none">Out of stock</div>
+<span id="qtySubTxt">More than 10 available</span></body></html>`, false},
+		{"quantity", `<html><body><span id="qtySubTxt">Out of Stock</span></body></html>`, true},
+		{"evo quantity", `<html><body><div class="x-quantity__availability evo"><span>Currently sold out</span></div></body></html>`, true},
+	}
+	for _, tt := range tests {
+		if _, matched := rule.matchPage(testPageState(t, tt.source)); matched != tt.matched {
+			t.Errorf("%s: matchPage() = %v, want %v", tt.name, matched, tt.matched)
+		}
+	}
+}
+
+func TestMatchListing(t *testing.T) {
+	rule := DefaultListingRules["ebay"]
+	status, _, ok := rule.matchListing(testPageState(t, `<html><body><div>This listing has ended.</div></body></html>`))
+	if !ok || status != LISTING_ENDED {
+		t.Errorf("matchListing() = %s, %v, want LISTING_ENDED", status, ok)
+	}
+	// out of stock is not a state of the opened page
+	if status, _, ok = rule.matchListing(testPageState(t, `<html><body><span id="qtySubTxt">Out of stock</span></body></html>`)); ok {
+		t.Errorf("matchListing() = %s, want no state", status)
+	}
+}
+
+func TestSiteDefinitionListingScope(t *testing.T) {
+	definition, err := LoadSiteDefinition("../sites/walmart.ini")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scope := definition.Site.ListingRule.OutOfStock.Scope; len(scope) != 1 || scope[0] != `//*[@data-testid="add-to-cart-section"]` {
+		t.Errorf("scope = %q", scope)
+	}
+}
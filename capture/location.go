@@ -0,0 +1,156 @@
+package capture
+
+import (
+	"fmt"
+	"github.com/tebeka/selenium"
+	"log"
+	"strings"
+	"time"
+)
+
+// LocationRule
+// @Description: How to set the delivery location of a site, by the ship-to dialog or by a cookie
+type LocationRule struct {
+	// Open is the xpath of buttons opening the ship-to dialog, the first displayed one is clicked
+	Open []string `ini:",,allowshadow" delim:"\n"`
+	// Input is the xpath of postal code inputs, the first displayed one is filled
+	Input []string `ini:",,allowshadow" delim:"\n"`
+	// Submit is the xpath of buttons applying the postal code, the input is submitted by enter key if none is found
+	Submit []string `ini:",,allowshadow" delim:"\n"`
+	// Cookie is the cookie carrying the delivery location instead of the dialog, exp: zip={postalCode},
+	// the page is reloaded after it is set
+	Cookie string
+	// Timeout is how long to wait for the elements of ship-to dialog
+	Timeout time.Duration
+}
+
+// DefaultLocationRules is used when a site is not configured
+var DefaultLocationRules = map[string]LocationRule{
+	"ebay": {
+		Open: []string{"//*[contains(@class, \"ux-labels-values--shipping\")]//button",
+			"//*[contains(@class, \"ux-labels-values--shipping\")]//a[contains(@class, \"ux-action\")]"},
+		Input:  []string{"//input[@id=\"shZipCode\"]", "//input[contains(@id, \"zipCode\")]", "//input[contains(@name, \"zipCode\")]"},
+		Submit: []string{"//*[@id=\"shGetRates\"]", "//button[contains(@class, \"shipping-calculator\")]"},
+	},
+}
+
+// defaultWaitTimeout is the timeout of waiting for the page, if a rule has none
+const defaultWaitTimeout = 10 * time.Second
+
+// waitDisplayed wait for the first displayed element matched by the xpaths, or css selectors
+func waitDisplayed(wd selenium.WebDriver, xpaths []string, timeout time.Duration) (selenium.WebElement, string, error) {
+	var found selenium.WebElement
+	var foundXPath string
+	err := wd.WaitWithTimeout(func(wd selenium.WebDriver) (bool, error) {
+		for _, xpath := range xpaths {
+			elems, err := wd.FindElements(selectorBy(xpath), xpath)
+			if err != nil {
+				continue
+			}
+			for _, elem := range elems {
+				if displayed, _ := elem.IsDisplayed(); displayed {
+					found, foundXPath = elem, xpath
+					return true, nil
+				}
+			}
+		}
+		return false, nil
+	}, timeout)
+	if err != nil {
+		return nil, "", fmt.Errorf("none of the xpaths is displayed in %v: %s", timeout, strings.Join(xpaths, ", "))
+	}
+	return found, foundXPath, nil
+}
+
+// waitLoaded wait for the document loaded after a navigation
+func waitLoaded(wd selenium.WebDriver, timeout time.Duration) {
+	// let the navigation start before the state is polled
+	time.Sleep(500 * time.Millisecond)
+	err := wd.WaitWithTimeout(func(wd selenium.WebDriver) (bool, error) {
+		state, err := wd.ExecuteScript("return document.readyState", nil)
+		return err == nil && state == "complete", nil
+	}, timeout)
+	if err != nil {
+		log.Println("web.wait_loaded:", err)
+	}
+}
+
+// setLocation apply the postal code to the opened page, then keep the source of the page reloaded
+//  @return bool whether to continue the capture, the status of result is LOCATION_ERROR if not
+func (site Site) setLocation(wd selenium.WebDriver, postalCode string, result *Capture) bool {
+	rule := site.LocationRule
+	timeout := rule.Timeout
+	if timeout <= 0 {
+		timeout = defaultWaitTimeout
+	}
+
+	var err error
+	switch {
+	case rule.Cookie != "":
+		err = setLocationCookie(wd, rule.Cookie, postalCode)
+	case len(rule.Input) > 0:
+		err = setLocationDialog(wd, rule, postalCode, timeout, result)
+	default:
+		err = fmt.Errorf("the site has no delivery location rule")
+	}
+	if err != nil {
+		log.Println("web.location:", err)
+		result.Status = string(LOCATION_ERROR)
+		result.Reason = err.Error()
+		return false
+	}
+	waitLoaded(wd, timeout)
+	log.Printf("web.location: %s", postalCode)
+	result.PostalCode = postalCode
+
+	// the price and shipping are read from the page of the location
+	refreshPageSource(wd, result)
+	dismissOverlays(wd, site.OverlayRule)
+	return true
+}
+
+// setLocationCookie set the cookie of the postal code, and reload the page
+func setLocationCookie(wd selenium.WebDriver, cookie string, postalCode string) error {
+	name, value, ok := strings.Cut(strings.ReplaceAll(cookie, "{postalCode}", postalCode), "=")
+	if !ok {
+		return fmt.Errorf("the location cookie %q is not name=value", cookie)
+	}
+	err := wd.AddCookie(&selenium.Cookie{Name: strings.TrimSpace(name), Value: strings.TrimSpace(value), Path: "/"})
+	if err != nil {
+		return err
+	}
+	return wd.Refresh()
+}
+
+// setLocationDialog fill the postal code into the ship-to dialog
+func setLocationDialog(wd selenium.WebDriver, rule LocationRule, postalCode string, timeout time.Duration, result *Capture) error {
+	if len(rule.Open) > 0 {
+		button, _, err := waitDisplayed(wd, rule.Open, timeout)
+		if err != nil {
+			result.FailedSelector = strings.Join(rule.Open, " | ")
+			return err
+		}
+		if err = button.Click(); err != nil {
+			return err
+		}
+	}
+
+	input, _, err := waitDisplayed(wd, rule.Input, timeout)
+	if err != nil {
+		result.FailedSelector = strings.Join(rule.Input, " | ")
+		return err
+	}
+	if err = input.Clear(); err != nil {
+		return err
+	}
+	if err = input.SendKeys(postalCode); err != nil {
+		return err
+	}
+
+	if len(rule.Submit) > 0 {
+		if button, _, err := waitDisplayed(wd, rule.Submit, timeout); err == nil {
+			return button.Click()
+		}
+	}
+	return input.SendKeys(selenium.EnterKey)
+}
@@ -0,0 +1,99 @@
+package capture
+
+import (
+	"github.com/tebeka/selenium"
+	"log"
+	"time"
+)
+
+// OverlayRule
+// @Description: The consent banners and popups of a site covering the page, dismissed before capture
+type OverlayRule struct {
+	// Click is the xpath of buttons to click, exp: the accept button of cookie consent.
+	// An xpath may contain ",", so the key is repeated for every xpath in config
+	Click []string `ini:",,allowshadow" delim:"\n"`
+	// Remove is the xpath of elements to remove from the page
+	Remove []string `ini:",,allowshadow" delim:"\n"`
+	// HideFixed indicates whether to hide all fixed and sticky positioned elements
+	HideFixed bool
+}
+
+// DefaultOverlayRules is used when a site is not configured
+var DefaultOverlayRules = map[string]OverlayRule{
+	"ebay": {
+		Click: []string{"//*[@id=\"gdpr-banner-accept\"]"},
+		Remove: []string{"//*[@id=\"gdpr-banner\"]", "//*[contains(@class, \"vi-signin-lightbox\")]",
+			"//*[contains(@class, \"lightbox-dialog\")]"},
+	},
+	"amazon": {
+		Click:  []string{"//*[@id=\"sp-cc-accept\"]"},
+		Remove: []string{"//*[@id=\"sp-cc\"]", "//*[contains(@class, \"a-modal-scroller\")]"},
+	},
+}
+
+// removeScript remove the elements matched by the xpath, return the number removed
+const removeScript = `
+var found = document.evaluate(arguments[0], document, null, XPathResult.ORDERED_NODE_SNAPSHOT_TYPE, null);
+for (var i = 0; i < found.snapshotLength; i++) {
+	var el = found.snapshotItem(i);
+	if (el.parentNode) { el.parentNode.removeChild(el); }
+}
+return found.snapshotLength;`
+
+// hideFixedScript hide the fixed and sticky positioned elements, and unlock the scrolling locked by modals
+const hideFixedScript = `
+var hidden = 0;
+var all = document.body ? document.body.getElementsByTagName("*") : [];
+for (var i = 0; i < all.length; i++) {
+	var position = window.getComputedStyle(all[i]).position;
+	if (position === "fixed" || position === "sticky") {
+		all[i].style.setProperty("display", "none", "important");
+		hidden++;
+	}
+}
+[document.documentElement, document.body].forEach(function (el) {
+	if (el) { el.style.setProperty("overflow", "visible", "important"); }
+});
+return hidden;`
+
+// dismissOverlays click and remove the overlays of rule, a missing overlay is skipped
+func dismissOverlays(wd selenium.WebDriver, rule OverlayRule) {
+	for _, xpath := range rule.Click {
+		elems, err := wd.FindElements(selenium.ByXPATH, xpath)
+		if err != nil {
+			continue
+		}
+		for _, elem := range elems {
+			if displayed, _ := elem.IsDisplayed(); !displayed {
+				continue
+			}
+			if err = elem.Click(); err != nil {
+				log.Printf("overlay.click %s error: %v", xpath, err)
+				continue
+			}
+			log.Printf("overlay.click %s", xpath)
+		}
+	}
+	if len(rule.Click) > 0 {
+		// let the banner fade out
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	for _, xpath := range rule.Remove {
+		removed, err := wd.ExecuteScript(removeScript, []interface{}{xpath})
+		if err != nil {
+			log.Printf("overlay.remove %s error: %v", xpath, err)
+			continue
+		}
+		log.Printf("overlay.remove %s: %v", xpath, removed)
+	}
+
+	if rule.HideFixed {
+		hidden, err := wd.ExecuteScript(hideFixedScript, nil)
+		if err != nil {
+			log.Printf("overlay.hide_fixed error: %v", err)
+			return
+		}
+		log.Printf("overlay.hide_fixed: %v", hidden)
+	}
+}
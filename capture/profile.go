@@ -0,0 +1,63 @@
+package capture
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// Profile
+// @Description: The browser fingerprint of a session, so that screenshots are consistent and match the target market
+type Profile struct {
+	// UserAgents is the pool of user agents, one is picked per session, empty to keep the one of browser
+	UserAgents []string `ini:",,allowshadow" delim:"\n"`
+	// Width is the window width of browser
+	Width int
+	// Height is the window height of browser
+	Height int
+	// Locale is the languages of Accept-Language and navigator.languages, exp: en-US, en
+	Locale string
+	// Timezone is the IANA time zone of browser, exp: America/New_York
+	Timezone string
+}
+
+// DefaultProfile is the profile of sites without one
+var DefaultProfile = Profile{Width: 1920, Height: 1080}
+
+// userAgent pick a user agent from the pool
+func (p Profile) userAgent() string {
+	if len(p.UserAgents) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(p.UserAgents[rand.Intn(len(p.UserAgents))])
+}
+
+// args is the firefox command line arguments of the window size, which headless firefox respects
+func (p Profile) args() []string {
+	if p.Width <= 0 || p.Height <= 0 {
+		return nil
+	}
+	return []string{fmt.Sprintf("--width=%d", p.Width), fmt.Sprintf("--height=%d", p.Height)}
+}
+
+// firefoxPrefs is the firefox preferences of the user agent and locale
+func (p Profile) firefoxPrefs() map[string]interface{} {
+	prefs := map[string]interface{}{}
+	if ua := p.userAgent(); ua != "" {
+		prefs["general.useragent.override"] = ua
+	}
+	if p.Locale != "" {
+		prefs["intl.accept_languages"] = p.Locale
+		// the first language is the locale of Intl and date formats
+		prefs["intl.locale.requested"] = strings.TrimSpace(strings.Split(p.Locale, ",")[0])
+	}
+	return prefs
+}
+
+// env is the environment variables of firefox process
+func (p Profile) env() map[string]string {
+	if p.Timezone == "" {
+		return nil
+	}
+	return map[string]string{"TZ": p.Timezone}
+}
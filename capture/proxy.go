@@ -0,0 +1,192 @@
+package capture
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Proxy is an HTTP or SOCKS proxy of the browser
+type Proxy struct {
+	// Scheme is http, socks4 or socks5
+	Scheme string
+	Host   string
+	Port   int
+}
+
+// ParseProxy parse proxy url, exp: http://10.0.0.1:3128, socks5://10.0.0.2:1080
+func ParseProxy(rawUrl string) (*Proxy, error) {
+	u, err := url.Parse(strings.TrimSpace(rawUrl))
+	if err != nil {
+		return nil, err
+	}
+	scheme := strings.ToLower(u.Scheme)
+	switch scheme {
+	case "http", "socks4", "socks5":
+	case "https":
+		// the manual proxy configuration of firefox connects to the proxy in plain text only
+		return nil, fmt.Errorf("proxy %s: https proxy is not supported, use http or socks5", rawUrl)
+	default:
+		return nil, fmt.Errorf("proxy %s: unsupported scheme %q", rawUrl, u.Scheme)
+	}
+	if u.User != nil {
+		// firefox prompts for the credentials, which can not be answered by WebDriver
+		return nil, fmt.Errorf("proxy %s: credentials are not supported, allow the worker ip on the proxy instead", u.Host)
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		return nil, fmt.Errorf("proxy %s: invalid port", rawUrl)
+	}
+	return &Proxy{Scheme: scheme, Host: u.Hostname(), Port: port}, nil
+}
+
+// String is the proxy url
+func (p *Proxy) String() string {
+	return fmt.Sprintf("%s://%s", p.Scheme, net.JoinHostPort(p.Host, strconv.Itoa(p.Port)))
+}
+
+// firefoxPrefs is the firefox preferences of manual proxy configuration
+func (p *Proxy) firefoxPrefs() map[string]interface{} {
+	prefs := map[string]interface{}{
+		"network.proxy.type":                      1,
+		"network.proxy.no_proxies_on":             "localhost, 127.0.0.1",
+		"network.proxy.allow_hijacking_localhost": false,
+	}
+	if strings.HasPrefix(p.Scheme, "socks") {
+		prefs["network.proxy.socks"] = p.Host
+		prefs["network.proxy.socks_port"] = p.Port
+		prefs["network.proxy.socks_version"] = 5
+		if p.Scheme == "socks4" {
+			prefs["network.proxy.socks_version"] = 4
+		}
+		// resolve the host names by the proxy, so that the dns is local to the country too
+		prefs["network.proxy.socks_remote_dns"] = true
+		return prefs
+	}
+	prefs["network.proxy.http"] = p.Host
+	prefs["network.proxy.http_port"] = p.Port
+	prefs["network.proxy.ssl"] = p.Host
+	prefs["network.proxy.ssl_port"] = p.Port
+	return prefs
+}
+
+// proxyHealth is the health of a proxy in pool
+type proxyHealth struct {
+	proxy         *Proxy
+	successes     int
+	failures      int
+	consecutively int
+	lastError     string
+	quarantine    time.Time
+}
+
+// ProxyPool is the proxies per country, rotated round-robin, a failed or blocked proxy is quarantined
+type ProxyPool struct {
+	mu        sync.Mutex
+	byCountry map[string][]*proxyHealth
+	next      map[string]int
+	// Quarantine is how long a failed proxy is skipped, multiplied by the consecutive failures, at most 8 times
+	Quarantine time.Duration
+}
+
+// DefaultProxyCountry is the proxies used by a country without its own proxies
+const DefaultProxyCountry = "default"
+
+// NewProxyPool make the pool
+//  @param byCountry the proxy urls per country, the key is case-insensitive
+//  @param quarantine how long a failed proxy is skipped
+func NewProxyPool(byCountry map[string][]string, quarantine time.Duration) (*ProxyPool, error) {
+	pool := &ProxyPool{byCountry: map[string][]*proxyHealth{}, next: map[string]int{}, Quarantine: quarantine}
+	for country, urls := range byCountry {
+		for _, rawUrl := range urls {
+			proxy, err := ParseProxy(rawUrl)
+			if err != nil {
+				return nil, err
+			}
+			key := strings.ToLower(country)
+			pool.byCountry[key] = append(pool.byCountry[key], &proxyHealth{proxy: proxy})
+		}
+	}
+	return pool, nil
+}
+
+// Pick select the next healthy proxy of the country,
+// if all are quarantined, the one released earliest is used
+//  @return *Proxy nil if there is no proxy for the country, then the browser connects directly
+func (pool *ProxyPool) Pick(country string) *Proxy {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	key := strings.ToLower(country)
+	proxies := pool.byCountry[key]
+	if len(proxies) == 0 {
+		key = DefaultProxyCountry
+		proxies = pool.byCountry[key]
+	}
+	if len(proxies) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	var earliest *proxyHealth
+	for i := 0; i < len(proxies); i++ {
+		health := proxies[(pool.next[key]+i)%len(proxies)]
+		if !health.quarantine.After(now) {
+			pool.next[key] = (pool.next[key] + i + 1) % len(proxies)
+			return health.proxy
+		}
+		if earliest == nil || health.quarantine.Before(earliest.quarantine) {
+			earliest = health
+		}
+	}
+	log.Printf("All proxies of %s are quarantined, use %s", key, earliest.proxy)
+	return earliest.proxy
+}
+
+func (pool *ProxyPool) health(proxy *Proxy) *proxyHealth {
+	for _, proxies := range pool.byCountry {
+		for _, health := range proxies {
+			if health.proxy == proxy {
+				return health
+			}
+		}
+	}
+	return nil
+}
+
+// Succeeded record a capture through the proxy succeeded
+func (pool *ProxyPool) Succeeded(proxy *Proxy) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	if health := pool.health(proxy); health != nil {
+		health.successes++
+		health.consecutively = 0
+		health.quarantine = time.Time{}
+	}
+}
+
+// Failed record a capture through the proxy failed or blocked, the proxy is quarantined,
+// so that the next capture rotates to another proxy
+func (pool *ProxyPool) Failed(proxy *Proxy, reason string) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	health := pool.health(proxy)
+	if health == nil {
+		return
+	}
+	health.failures++
+	health.consecutively++
+	health.lastError = reason
+	factor := health.consecutively
+	if factor > 8 {
+		factor = 8
+	}
+	health.quarantine = time.Now().Add(pool.Quarantine * time.Duration(factor))
+	log.Printf("Proxy %s failed: %s, successes: %d, failures: %d, quarantined until %s", proxy, reason,
+		health.successes, health.failures, health.quarantine.Format(time.RFC3339))
+}
@@ -0,0 +1,123 @@
+package capture
+
+import (
+	"log"
+	"sort"
+	"strings"
+)
+
+// SpecificsLayout
+// @Description: Where the key/value pairs of item specifics are, the label and value xpaths are relative to the row
+type SpecificsLayout struct {
+	Row   string
+	Label string
+	Value string
+}
+
+// EBAY_SPECIFICS_LAYOUTS is the layouts of item specifics section, tried in order until one has rows
+var EBAY_SPECIFICS_LAYOUTS = []SpecificsLayout{
+	{
+		Row:   "//*[@id=\"viTabs_0_is\"]//dl[contains(@class, \"ux-labels-values\")]",
+		Label: ".//dt",
+		Value: ".//dd",
+	},
+	{
+		Row:   "//*[contains(@class, \"ux-layout-section-evo\")]//*[contains(@class, \"ux-labels-values\")][.//*[contains(@class, \"ux-labels-values__labels\")]]",
+		Label: ".//*[contains(@class, \"ux-labels-values__labels\")]",
+		Value: ".//*[contains(@class, \"ux-labels-values__values\")]",
+	},
+	{
+		Row:   "//*[@id=\"viTabs_0_is\"]//*[contains(@class, \"ux-layout-section__row\")]//*[contains(@class, \"ux-labels-values__labels\")]/..",
+		Label: ".//*[contains(@class, \"ux-labels-values__labels\")]",
+		Value: ".//*[contains(@class, \"ux-labels-values__values\")]",
+	},
+}
+
+// normalizeSpecific collapse the spaces, and trim the colon of label
+func normalizeSpecific(text string) string {
+	return strings.TrimSpace(strings.TrimSuffix(strings.Join(strings.Fields(text), " "), ":"))
+}
+
+// extractSpecifics get the item specifics by the first layout having rows
+//  @return map[string]string the value by label, nil if the page has no item specifics
+func extractSpecifics(f finder, layouts []SpecificsLayout) map[string]string {
+	for _, layout := range layouts {
+		rows, err := f.FindRows(layout.Row, layout.Label, layout.Value)
+		if err != nil || len(rows) == 0 {
+			continue
+		}
+		specifics := map[string]string{}
+		for _, row := range rows {
+			label := normalizeSpecific(row[0])
+			if label == "" {
+				continue
+			}
+			specifics[label] = normalizeSpecific(row[1])
+		}
+		if len(specifics) > 0 {
+			return specifics
+		}
+	}
+	log.Println("item specifics not found")
+	return nil
+}
+
+// SpecificMismatch
+// @Description: An item specific not matching the expected value of request
+type SpecificMismatch struct {
+	Name     string `json:"name"`
+	Expected string `json:"expected"`
+	// Actual is empty if the listing does not have the item specific
+	Actual string `json:"actual"`
+}
+
+// ValidateSpecifics compare the item specifics with the expected values,
+// the names and values are compared case-insensitively with the spaces collapsed,
+// and the leading zeros of codes are ignored, exp: UPC 012345678905 matches EAN 12345678905
+//  @return []SpecificMismatch the mismatched and missing item specifics
+func ValidateSpecifics(specifics map[string]string, expected map[string]string) []SpecificMismatch {
+	var mismatches []SpecificMismatch
+	for name, want := range expected {
+		actual, ok := findSpecific(specifics, name)
+		if !ok || !sameSpecific(actual, want) {
+			mismatches = append(mismatches, SpecificMismatch{Name: name, Expected: want, Actual: actual})
+		}
+	}
+	sort.Slice(mismatches, func(i, j int) bool { return mismatches[i].Name < mismatches[j].Name })
+	return mismatches
+}
+
+// findSpecific get the value of item specific by the case-insensitive name
+func findSpecific(specifics map[string]string, name string) (string, bool) {
+	name = normalizeSpecific(name)
+	for label, value := range specifics {
+		if strings.EqualFold(label, name) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// sameSpecific compare the values of item specific
+func sameSpecific(actual string, expected string) bool {
+	actual, expected = normalizeSpecific(actual), normalizeSpecific(expected)
+	if strings.EqualFold(actual, expected) {
+		return true
+	}
+	if isDigits(actual) && isDigits(expected) {
+		return strings.TrimLeft(actual, "0") == strings.TrimLeft(expected, "0")
+	}
+	return false
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
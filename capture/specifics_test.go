@@ -0,0 +1,77 @@
+package capture
+
+import (
+	"reflect"
+	"testing"
+	"y-clouds.com/tarantula/dom"
+)
+
+func TestValidateSpecifics(t *testing.T) {
+	specifics := map[string]string{"Brand": "Apple", "Storage Capacity": "64  GB", "UPC": "012345678905", "Color": "Black"}
+
+	matched := map[string]string{
+		"brand":               "APPLE",
+		"Storage Capacity:":   "64 GB",
+		" storage  capacity ": " 64 gb ",
+		"UPC":                 "12345678905",
+	}
+	if got := ValidateSpecifics(specifics, matched); len(got) != 0 {
+		t.Errorf("ValidateSpecifics() of the matched values = %+v, want none", got)
+	}
+
+	expected := map[string]string{"Brand": "Samsung", "Model": "Galaxy", "UPC": "112345678905", "Color": "black"}
+	want := []SpecificMismatch{
+		{Name: "Brand", Expected: "Samsung", Actual: "Apple"},
+		{Name: "Model", Expected: "Galaxy", Actual: ""},
+		{Name: "UPC", Expected: "112345678905", Actual: "012345678905"},
+	}
+	if got := ValidateSpecifics(specifics, expected); !reflect.DeepEqual(got, want) {
+		t.Errorf("ValidateSpecifics() = %+v, want %+v", got, want)
+	}
+
+	// the listing without item specifics misses every expected one
+	if got := ValidateSpecifics(nil, map[string]string{"Brand": "Apple"}); len(got) != 1 || got[0].Actual != "" {
+		t.Errorf("ValidateSpecifics() without specifics = %+v, want Brand missing", got)
+	}
+	if got := ValidateSpecifics(specifics, nil); got != nil {
+		t.Errorf("ValidateSpecifics() without expected = %+v, want nil", got)
+	}
+}
+
+func TestSameSpecific(t *testing.T) {
+	tests := []struct {
+		actual, expected string
+		same             bool
+	}{
+		{"Apple", "apple", true},
+		{"New  with tags", "new with tags", true},
+		{"0012", "12", true},
+		{"000", "0", true},
+		{"0012", "012a", false},
+		{"", "", true},
+		{"Black", "Blue", false},
+	}
+	for _, tt := range tests {
+		if got := sameSpecific(tt.actual, tt.expected); got != tt.same {
+			t.Errorf("sameSpecific(%q, %q) = %v, want %v", tt.actual, tt.expected, got, tt.same)
+		}
+	}
+}
+
+func TestExtractSpecifics(t *testing.T) {
+	root, err := dom.Parse(`<html><body><div id="viTabs_0_is"><div class="ux-layout-section__row">
+<div class="ux-labels-values__labels">Brand:</div><div class="ux-labels-values__values">Apple</div>
+<div class="ux-labels-values__labels">  </div><div class="ux-labels-values__values">no label</div>
+</div></div></body></html>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := extractSpecifics(htmlFinder{root: root}, EBAY_SPECIFICS_LAYOUTS); !reflect.DeepEqual(got, map[string]string{"Brand": "Apple"}) {
+		t.Errorf("extractSpecifics() = %v, want Brand: Apple", got)
+	}
+
+	root, _ = dom.Parse("<html><body>no specifics</body></html>")
+	if got := extractSpecifics(htmlFinder{root: root}, EBAY_SPECIFICS_LAYOUTS); got != nil {
+		t.Errorf("extractSpecifics() of a page without specifics = %v, want nil", got)
+	}
+}
@@ -0,0 +1,251 @@
+package capture
+
+import (
+	"fmt"
+	"github.com/tebeka/selenium"
+	"log"
+	"strings"
+	"time"
+)
+
+// step actions
+const (
+	STEP_CLICK  = "click"
+	STEP_SCROLL = "scroll"
+	STEP_WAIT   = "wait"
+	STEP_TYPE   = "type"
+	STEP_SCRIPT = "script"
+	STEP_HOVER  = "hover"
+	STEP_SLEEP  = "sleep"
+)
+
+// Step
+// @Description: An interaction executed before extraction and screenshots, exp: expand the full description
+type Step struct {
+	// Action is click, scroll, wait, type, script, hover or sleep
+	Action string `json:"action"`
+	// Selector is the element of action, a css selector or an xpath starting with /,
+	// scroll without selector scrolls to the bottom of page, script gets the element as arguments[0]
+	Selector string `json:"selector,omitempty"`
+	// Text is the text typed, the javascript executed, or the duration slept, exp: 2s, at most MaxStepWait
+	Text string `json:"text,omitempty"`
+	// Timeout is how long to wait for the element, exp: 5s, default 10s,
+	// the scroll to the bottom of page is stopped after it, default 30s, at most MaxStepWait
+	Timeout string `json:"timeout,omitempty"`
+	// Optional indicates a failure of the step does not fail the capture
+	Optional bool `json:"optional,omitempty"`
+}
+
+// StepResult
+// @Description: How a step is executed
+type StepResult struct {
+	Action   string `json:"action"`
+	Selector string `json:"selector,omitempty"`
+	// Elapsed is the milliseconds the step takes
+	Elapsed int64  `json:"elapsed"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ParseStep parse the step of a line, the action is followed by the selector, and " => " the text:
+//  click #see-full-description
+//  type(5s) input[name="zip"] => 10001
+//  scroll? //*[@id="desc"]
+//  script => window.scrollTo(0, document.body.scrollHeight)
+//  sleep => 2s
+// the timeout is in the parentheses of action, and "?" marks the step optional
+func ParseStep(line string) (Step, error) {
+	step := Step{}
+	line = strings.TrimSpace(line)
+	if i := strings.Index(line, " => "); i >= 0 {
+		line, step.Text = strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+4:])
+	}
+	action := line
+	if i := strings.IndexAny(line, " \t"); i >= 0 {
+		action, step.Selector = line[:i], strings.TrimSpace(line[i+1:])
+	}
+	if strings.HasSuffix(action, "?") {
+		action, step.Optional = strings.TrimSuffix(action, "?"), true
+	}
+	if i := strings.Index(action, "("); i >= 0 && strings.HasSuffix(action, ")") {
+		action, step.Timeout = action[:i], action[i+1:len(action)-1]
+	}
+	step.Action = strings.ToLower(action)
+	return step, step.validate()
+}
+
+// MaxStepWait is the longest timeout and sleep of a step, so that a request can not hold the browser for long
+var MaxStepWait = time.Minute
+
+// parseWait parse the timeout or the sleep of a step, at most MaxStepWait
+func parseWait(action string, name string, value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return fmt.Errorf("step %s: invalid %s %q", action, name, value)
+	}
+	if d > MaxStepWait {
+		return fmt.Errorf("step %s: %s %s is longer than %v, see MaxWait of [Steps]", action, name, value, MaxStepWait)
+	}
+	return nil
+}
+
+// validate check the action and its arguments
+func (step Step) validate() error {
+	if step.Timeout != "" {
+		if err := parseWait(step.Action, "timeout", step.Timeout); err != nil {
+			return err
+		}
+	}
+	switch step.Action {
+	case STEP_CLICK, STEP_WAIT, STEP_HOVER, STEP_TYPE:
+		if step.Selector == "" {
+			return fmt.Errorf("step %s: no selector", step.Action)
+		}
+	case STEP_SCRIPT:
+		if step.Text == "" {
+			return fmt.Errorf("step %s: no script", step.Action)
+		}
+	case STEP_SLEEP:
+		if err := parseWait(step.Action, "duration", step.Text); err != nil {
+			return err
+		}
+	case STEP_SCROLL:
+	default:
+		return fmt.Errorf("unknown step action: %q", step.Action)
+	}
+	return nil
+}
+
+// scrollBottomTimeout is how long the scroll to the bottom of page takes at most, exp: an infinite feed
+const scrollBottomTimeout = 30 * time.Second
+
+// scrollBottomScript scroll to the bottom of page step by step, so that the lazy contents are loaded,
+// it is stopped after arguments[0] milliseconds, and returns whether the bottom is reached
+const scrollBottomScript = `
+var done = arguments[arguments.length - 1], deadline = Date.now() + arguments[0], y = 0;
+var timer = setInterval(function () {
+	window.scrollBy(0, window.innerHeight);
+	y += window.innerHeight;
+	var bottom = y >= document.body.scrollHeight;
+	if (bottom || Date.now() >= deadline) {
+		clearInterval(timer);
+		window.scrollTo(0, 0);
+		done(bottom);
+	}
+}, 200);`
+
+// scrollBottom scroll to the bottom of page, stopped after the timeout
+func scrollBottom(wd selenium.WebDriver, timeout time.Duration) error {
+	// the script returns by itself at the timeout, the driver waits a little longer for it
+	if err := wd.SetAsyncScriptTimeout(timeout + 5*time.Second); err != nil {
+		return err
+	}
+	bottom, err := wd.ExecuteScriptAsync(scrollBottomScript, []interface{}{timeout.Milliseconds()})
+	if err != nil {
+		return err
+	}
+	if reached, _ := bottom.(bool); !reached {
+		log.Printf("web.scroll: the bottom is not reached in %v", timeout)
+	}
+	return nil
+}
+
+// run execute the step
+func (step Step) run(wd selenium.WebDriver) error {
+	if err := step.validate(); err != nil {
+		return err
+	}
+	timeout := defaultWaitTimeout
+	if step.Timeout != "" {
+		timeout, _ = time.ParseDuration(step.Timeout)
+	}
+
+	var elem selenium.WebElement
+	if step.Selector != "" {
+		var err error
+		if elem, _, err = waitDisplayed(wd, []string{step.Selector}, timeout); err != nil {
+			return err
+		}
+	}
+
+	switch step.Action {
+	case STEP_CLICK:
+		return elem.Click()
+	case STEP_SCROLL:
+		if elem == nil {
+			if step.Timeout == "" {
+				timeout = scrollBottomTimeout
+				if timeout > MaxStepWait {
+					timeout = MaxStepWait
+				}
+			}
+			return scrollBottom(wd, timeout)
+		}
+		_, err := wd.ExecuteScript("arguments[0].scrollIntoView({block: \"center\"});", []interface{}{elem})
+		return err
+	case STEP_TYPE:
+		if err := elem.Clear(); err != nil {
+			return err
+		}
+		return elem.SendKeys(step.Text)
+	case STEP_SCRIPT:
+		var args []interface{}
+		if elem != nil {
+			args = append(args, elem)
+		}
+		_, err := wd.ExecuteScript(step.Text, args)
+		return err
+	case STEP_HOVER:
+		return elem.MoveTo(0, 0)
+	case STEP_SLEEP:
+		duration, _ := time.ParseDuration(step.Text)
+		time.Sleep(duration)
+	}
+	return nil
+}
+
+// CheckScriptSteps check the steps of request are allowed to execute javascript
+//  @param allowScript whether the script steps are allowed
+//  @return error if a script step is not allowed
+func CheckScriptSteps(steps []Step, allowScript bool) error {
+	if allowScript {
+		return nil
+	}
+	for i, step := range steps {
+		if strings.EqualFold(step.Action, STEP_SCRIPT) {
+			return fmt.Errorf("step %d %s: script steps are not allowed, see AllowScript of [Steps]", i+1, step.Action)
+		}
+	}
+	return nil
+}
+
+// runSteps execute the steps in order, until a step not optional fails,
+// then refresh the page source of result, as the page is changed by the steps
+//  @return bool whether to continue the capture, the status of result is STEP_ERROR if not
+func runSteps(wd selenium.WebDriver, steps []Step, result *Capture) bool {
+	for i, step := range steps {
+		started := time.Now()
+		err := step.run(wd)
+		stepResult := StepResult{Action: step.Action, Selector: step.Selector, Elapsed: time.Since(started).Milliseconds()}
+		if err != nil {
+			stepResult.Error = err.Error()
+		}
+		result.Steps = append(result.Steps, stepResult)
+		if err == nil {
+			continue
+		}
+
+		log.Printf("web.step %d %s %s: %v", i+1, step.Action, step.Selector, err)
+		if !step.Optional {
+			result.Status = string(STEP_ERROR)
+			result.Reason = fmt.Sprintf("step %d %s: %v", i+1, step.Action, err)
+			result.FailedSelector = step.Selector
+			return false
+		}
+	}
+	// let the page settle after the interactions
+	if len(steps) > 0 {
+		waitLoaded(wd, defaultWaitTimeout)
+		refreshPageSource(wd, result)
+	}
+	return true
+}
@@ -0,0 +1,74 @@
+package capture
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseStep(t *testing.T) {
+	tests := []struct {
+		line string
+		want Step
+	}{
+		{"click #see-full-description", Step{Action: STEP_CLICK, Selector: "#see-full-description"}},
+		{`type(5s) input[name="zip"] => 10001`, Step{Action: STEP_TYPE, Selector: `input[name="zip"]`, Text: "10001", Timeout: "5s"}},
+		{`scroll? //*[@id="desc"]`, Step{Action: STEP_SCROLL, Selector: `//*[@id="desc"]`, Optional: true}},
+		{"scroll", Step{Action: STEP_SCROLL}},
+		{"scroll(1m)?", Step{Action: STEP_SCROLL, Timeout: "1m", Optional: true}},
+		{"script => window.scrollTo(0, document.body.scrollHeight)", Step{Action: STEP_SCRIPT, Text: "window.scrollTo(0, document.body.scrollHeight)"}},
+		{"  SLEEP => 2s ", Step{Action: STEP_SLEEP, Text: "2s"}},
+		{"hover\t//nav//li[contains(., \"Menu\")]", Step{Action: STEP_HOVER, Selector: `//nav//li[contains(., "Menu")]`}},
+		{"wait(3s)? .price => ignored", Step{Action: STEP_WAIT, Selector: ".price", Text: "ignored", Timeout: "3s", Optional: true}},
+	}
+	for _, tt := range tests {
+		got, err := ParseStep(tt.line)
+		if err != nil {
+			t.Errorf("ParseStep(%q) error: %v", tt.line, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseStep(%q) = %+v, want %+v", tt.line, got, tt.want)
+		}
+	}
+
+	for _, line := range []string{"", "click", "type => 10001", "script", "sleep => soon", "wait(later) .price", "drag #item"} {
+		if _, err := ParseStep(line); err == nil {
+			t.Errorf("ParseStep(%q), want error", line)
+		}
+	}
+}
+
+func TestStepMaxWait(t *testing.T) {
+	defer func(maxWait time.Duration) { MaxStepWait = maxWait }(MaxStepWait)
+	MaxStepWait = 30 * time.Second
+
+	for _, line := range []string{"sleep => 30s", "wait(30s) .price", "scroll(500ms)", "sleep => 0s"} {
+		if _, err := ParseStep(line); err != nil {
+			t.Errorf("ParseStep(%q) error: %v", line, err)
+		}
+	}
+	for _, line := range []string{"sleep => 31s", "sleep => 1h", "wait(2m) .price", "scroll(24h)", "sleep => -1s", "click(-5s) #more"} {
+		if _, err := ParseStep(line); err == nil {
+			t.Errorf("ParseStep(%q), want error", line)
+		}
+	}
+
+	// the steps of a request are checked before they are run
+	step := Step{Action: STEP_SLEEP, Text: "10m"}
+	if err := step.validate(); err == nil {
+		t.Errorf("validate() of %+v, want error", step)
+	}
+}
+
+func TestCheckScriptSteps(t *testing.T) {
+	steps := []Step{{Action: STEP_CLICK, Selector: "#more"}, {Action: STEP_SCRIPT, Text: "window.stop()"}}
+	if err := CheckScriptSteps(steps, false); err == nil {
+		t.Errorf("CheckScriptSteps() of a script step not allowed, want error")
+	}
+	if err := CheckScriptSteps(steps, true); err != nil {
+		t.Errorf("CheckScriptSteps() allowed = %v", err)
+	}
+	if err := CheckScriptSteps(steps[:1], false); err != nil {
+		t.Errorf("CheckScriptSteps() without script = %v", err)
+	}
+}
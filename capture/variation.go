@@ -0,0 +1,234 @@
+package capture
+
+import (
+	"errors"
+	"github.com/tebeka/selenium"
+	"log"
+	"sort"
+	"time"
+)
+
+// EBAY_VARIATION_XPATH is the xpath of the select boxes of variations, exp: size, color
+const EBAY_VARIATION_XPATH = "//select[starts-with(@id, \"msku-sel-\")] | //select[contains(@class, \"x-msku__select-box\")]"
+
+// MaxVariations is the max number of variations enumerated, a listing of more combinations is truncated
+const MaxVariations = 100
+
+// Variation
+// @Description: A variation of listing and its price
+type Variation struct {
+	// Options is the option by attribute name, exp: {"Size": "M", "Color": "Red"}
+	Options map[string]string `json:"options"`
+	Price   float32           `json:"price"`
+	// Available is false if an option is disabled, exp: out of stock
+	Available bool `json:"available"`
+}
+
+// variationDimension is a select box of variation
+type variationDimension struct {
+	Name    string
+	Options []string
+}
+
+// variationLabelScript is the label of a select box: selectboxlabel, aria-label, <label for> or name
+const variationLabelScript = `
+function label(sel) {
+	var name = sel.getAttribute("selectboxlabel") || sel.getAttribute("aria-label") || "";
+	if (!name && sel.id) {
+		var l = document.querySelector('label[for="' + sel.id + '"]');
+		if (l) { name = l.textContent; }
+	}
+	if (!name) { name = sel.getAttribute("name") || ""; }
+	return name.replace(/\s+/g, " ").replace(/:\s*$/, "").trim();
+}
+function text(o) { return o.text.replace(/\s+/g, " ").trim(); }
+function placeholder(o) { return o.value === "-1" || o.value === "" || /^-.*-$/.test(text(o)); }
+var found = document.evaluate(arguments[0], document, null, XPathResult.ORDERED_NODE_SNAPSHOT_TYPE, null);
+`
+
+// listVariationsScript return the select boxes of variations, with their options
+const listVariationsScript = variationLabelScript + `
+var result = [];
+for (var i = 0; i < found.snapshotLength; i++) {
+	var sel = found.snapshotItem(i), options = [];
+	for (var j = 0; j < sel.options.length; j++) {
+		if (!placeholder(sel.options[j])) { options.push(text(sel.options[j])); }
+	}
+	result.push({name: label(sel), options: options});
+}
+return result;`
+
+// selectVariationScript select the option of the select box named arguments[1] by text arguments[2],
+// return an error message or empty
+const selectVariationScript = variationLabelScript + `
+var name = arguments[1].toLowerCase(), value = arguments[2].toLowerCase();
+for (var i = 0; i < found.snapshotLength; i++) {
+	var sel = found.snapshotItem(i);
+	if (label(sel).toLowerCase() !== name) { continue; }
+	for (var j = 0; j < sel.options.length; j++) {
+		var o = sel.options[j];
+		if (placeholder(o) || text(o).toLowerCase() !== value) { continue; }
+		if (o.disabled) { return "option " + arguments[2] + " of " + arguments[1] + " is not available"; }
+		sel.selectedIndex = j;
+		sel.dispatchEvent(new Event("input", {bubbles: true}));
+		sel.dispatchEvent(new Event("change", {bubbles: true}));
+		return "";
+	}
+	return "option " + arguments[2] + " of " + arguments[1] + " not found";
+}
+return "variation " + arguments[1] + " not found";`
+
+// selectedIndexesScript return the selected index of every select box of variations
+const selectedIndexesScript = variationLabelScript + `
+var result = [];
+for (var i = 0; i < found.snapshotLength; i++) { result.push(found.snapshotItem(i).selectedIndex); }
+return result;`
+
+// restoreIndexesScript select the indexes arguments[1] of the select boxes of variations
+const restoreIndexesScript = variationLabelScript + `
+var indexes = arguments[1];
+for (var i = 0; i < found.snapshotLength && i < indexes.length; i++) {
+	var sel = found.snapshotItem(i);
+	if (sel.selectedIndex === indexes[i]) { continue; }
+	sel.selectedIndex = indexes[i];
+	sel.dispatchEvent(new Event("input", {bubbles: true}));
+	sel.dispatchEvent(new Event("change", {bubbles: true}));
+}`
+
+// listVariations get the select boxes of variations
+func listVariations(wd selenium.WebDriver, xpath string) ([]variationDimension, error) {
+	found, err := wd.ExecuteScript(listVariationsScript, []interface{}{xpath})
+	if err != nil {
+		return nil, err
+	}
+	items, _ := found.([]interface{})
+	dimensions := make([]variationDimension, 0, len(items))
+	for _, item := range items {
+		box, _ := item.(map[string]interface{})
+		dimension := variationDimension{}
+		dimension.Name, _ = box["name"].(string)
+		options, _ := box["options"].([]interface{})
+		for _, option := range options {
+			if text, ok := option.(string); ok {
+				dimension.Options = append(dimension.Options, text)
+			}
+		}
+		dimensions = append(dimensions, dimension)
+	}
+	return dimensions, nil
+}
+
+// selectVariation select the options by attribute name, then wait for the price updated
+func selectVariation(wd selenium.WebDriver, xpath string, options map[string]string) error {
+	// select in the order of names, so that the same variation is selected the same way
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		message, err := wd.ExecuteScript(selectVariationScript, []interface{}{xpath, name, options[name]})
+		if err != nil {
+			return err
+		}
+		if message, _ := message.(string); message != "" {
+			return errors.New(message)
+		}
+	}
+	// the price and pictures of variation are updated by the script of page
+	time.Sleep(time.Second)
+	waitLoaded(wd, defaultWaitTimeout)
+	return nil
+}
+
+// variationCombinations make the combinations of the options of every select box, at most MaxVariations
+func variationCombinations(dimensions []variationDimension) []map[string]string {
+	combinations := []map[string]string{{}}
+	truncated := false
+	for _, dimension := range dimensions {
+		var next []map[string]string
+	combine:
+		for _, combination := range combinations {
+			for _, option := range dimension.Options {
+				if len(next) >= MaxVariations {
+					truncated = true
+					break combine
+				}
+				options := map[string]string{dimension.Name: option}
+				for name, value := range combination {
+					options[name] = value
+				}
+				next = append(next, options)
+			}
+		}
+		if len(next) > 0 {
+			combinations = next
+		}
+	}
+	if truncated {
+		log.Printf("variations are truncated to %d", MaxVariations)
+	}
+	if len(combinations) == 1 && len(combinations[0]) == 0 {
+		return nil
+	}
+	return combinations
+}
+
+// enumerateVariations select every variation, and read its price,
+// then restore the selection of page, so that the page is captured as it was opened
+//  @param price read the price of the selected variation
+func enumerateVariations(wd selenium.WebDriver, xpath string, price func(wd selenium.WebDriver) (float32, error)) []Variation {
+	dimensions, err := listVariations(wd, xpath)
+	if err != nil {
+		log.Println("web.list_variations:", err)
+		return nil
+	}
+	indexes, err := wd.ExecuteScript(selectedIndexesScript, []interface{}{xpath})
+	if err != nil {
+		log.Println("web.selected_variation:", err)
+	}
+	defer func() {
+		if indexes == nil {
+			return
+		}
+		if _, err := wd.ExecuteScript(restoreIndexesScript, []interface{}{xpath, indexes}); err != nil {
+			log.Println("web.restore_variation:", err)
+			return
+		}
+		time.Sleep(time.Second)
+		waitLoaded(wd, defaultWaitTimeout)
+	}()
+	var variations []Variation
+	for _, options := range variationCombinations(dimensions) {
+		variation := Variation{Options: options}
+		if err = selectVariation(wd, xpath, options); err != nil {
+			log.Printf("web.select_variation %v: %v", options, err)
+		} else if variation.Price, err = price(wd); err == nil {
+			variation.Available = true
+		}
+		variations = append(variations, variation)
+	}
+	return variations
+}
+
+// applyVariations enumerate the variations if asked, then select the variation of request,
+// the page source of result is refreshed, as the page is updated by the selection
+//  @return bool whether to continue the capture, the status of result is VARIATION_ERROR if not
+func applyVariations(wd selenium.WebDriver, xpath string, selection map[string]string, enumerate bool,
+	price func(wd selenium.WebDriver) (float32, error), result *Capture) bool {
+	if enumerate {
+		result.Variations = enumerateVariations(wd, xpath, price)
+	}
+	if len(selection) > 0 {
+		if err := selectVariation(wd, xpath, selection); err != nil {
+			log.Println("web.select_variation:", err)
+			result.Status = string(VARIATION_ERROR)
+			result.Reason = err.Error()
+			result.FailedSelector = xpath
+			return false
+		}
+		result.Variation = selection
+	}
+	refreshPageSource(wd, result)
+	return true
+}
@@ -0,0 +1,58 @@
+package capture
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestVariationCombinations(t *testing.T) {
+	if got := variationCombinations(nil); got != nil {
+		t.Errorf("variationCombinations() without select boxes = %v, want nil", got)
+	}
+
+	got := variationCombinations([]variationDimension{
+		{Name: "Color", Options: []string{"Black", "White"}},
+		{Name: "Size", Options: []string{"S", "M"}},
+	})
+	want := []map[string]string{
+		{"Color": "Black", "Size": "S"}, {"Color": "Black", "Size": "M"},
+		{"Color": "White", "Size": "S"}, {"Color": "White", "Size": "M"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("variationCombinations() = %v, want %v", got, want)
+	}
+
+	// a select box without options is skipped
+	got = variationCombinations([]variationDimension{{Name: "Color", Options: []string{"Black"}}, {Name: "Size"}})
+	if !reflect.DeepEqual(got, []map[string]string{{"Color": "Black"}}) {
+		t.Errorf("variationCombinations() with an empty select box = %v", got)
+	}
+}
+
+func TestVariationCombinationsTruncated(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	options := make([]string, 30)
+	for i := range options {
+		options[i] = fmt.Sprint(i)
+	}
+	got := variationCombinations([]variationDimension{{Name: "A", Options: options}, {Name: "B", Options: options}, {Name: "C", Options: options}})
+	if len(got) != MaxVariations {
+		t.Errorf("variationCombinations() = %d combinations, want %d", len(got), MaxVariations)
+	}
+	for _, combination := range got {
+		if len(combination) != 3 {
+			t.Fatalf("combination %v, want an option of every select box", combination)
+		}
+	}
+	if n := strings.Count(logs.String(), "variations are truncated"); n != 1 {
+		t.Errorf("truncation is logged %d times, want once", n)
+	}
+}
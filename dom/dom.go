@@ -0,0 +1,99 @@
+package dom
+
+import (
+	"fmt"
+	"github.com/antchfx/htmlquery"
+	"golang.org/x/net/html"
+	"strings"
+)
+
+// Parse parse the html document like a browser, a malformed document is repaired instead of rejected
+func Parse(src string) (*html.Node, error) {
+	return html.Parse(strings.NewReader(src))
+}
+
+// FindAll get the nodes matched by the xpath expression, relative paths are evaluated from the node
+//  @return error if the expression is not a valid xpath
+func FindAll(n *html.Node, expr string) ([]*html.Node, error) {
+	return htmlquery.QueryAll(n, expr)
+}
+
+// Find get the first node matched by the xpath expression
+//  @return error if the expression is not a valid xpath, or no node matches
+func Find(n *html.Node, expr string) (*html.Node, error) {
+	found, err := htmlquery.Query(n, expr)
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no element matches %s", expr)
+	}
+	return found, nil
+}
+
+// Attr get the attribute value of element
+func Attr(n *html.Node, name string) string {
+	return htmlquery.SelectAttr(n, strings.ToLower(name))
+}
+
+// hiddenText is the elements whose text is not rendered
+var hiddenText = map[string]bool{"script": true, "style": true, "noscript": true, "template": true, "head": true}
+
+// blockElements break lines around their text
+var blockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "div": true, "dl": true, "dt": true,
+	"dd": true, "fieldset": true, "footer": true, "form": true, "h1": true, "h2": true, "h3": true, "h4": true,
+	"h5": true, "h6": true, "header": true, "hr": true, "li": true, "main": true, "nav": true, "ol": true,
+	"p": true, "pre": true, "section": true, "table": true, "tr": true, "ul": true,
+}
+
+// sourceBreaks is the whitespaces of source rendered as a space
+var sourceBreaks = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ", "\t", " ")
+
+// Text get the rendered text of node like the innerText of browser: script and style are skipped,
+// whitespaces are collapsed, block elements and <br> break lines, table cells are separated by a space
+func Text(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return strings.Join(strings.Fields(n.Data), " ")
+	}
+
+	var sb strings.Builder
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		switch {
+		case node.Type == html.TextNode:
+			// a line break of source is a space, lines are broken by elements only
+			sb.WriteString(sourceBreaks.Replace(node.Data))
+			return
+		case node.Type == html.ElementNode && hiddenText[node.Data]:
+			return
+		case node.Type == html.ElementNode && node.Data == "br":
+			sb.WriteString("\n")
+			return
+		case node.Type != html.ElementNode && node.Type != html.DocumentNode:
+			return
+		}
+		block := node.Type == html.ElementNode && blockElements[node.Data]
+		if block {
+			sb.WriteString("\n")
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+			if child.Type == html.ElementNode && (child.Data == "td" || child.Data == "th") {
+				sb.WriteString(" ")
+			}
+		}
+		if block {
+			sb.WriteString("\n")
+		}
+	}
+	walk(n)
+
+	var lines []string
+	for _, line := range strings.Split(sb.String(), "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
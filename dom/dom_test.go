@@ -0,0 +1,107 @@
+package dom
+
+import (
+	"golang.org/x/net/html"
+	"testing"
+)
+
+const testPage = `<!DOCTYPE html>
+<html><head><title>Item</title><style>.a{color:red}</style></head>
+<body>
+<div id="main"><h1 class="title  main">  Apple
+  iPhone </h1>
+<p>Line one<br>Line two</p>
+<script>var hidden = "script";</script>
+<ul><li>first<li>second<li>third</ul>
+<table><tr><td>Brand:</td><td>Apple</td></tr><tr><td>Color:</td><td>Black</td></tr></table>
+<dl><dt>Condition</dt><dd>New</dd></dl>
+<a href="/itm/123?hash=1" class="link">Link</a>
+</div>
+</body></html>`
+
+func parseTestPage(t *testing.T) *html.Node {
+	t.Helper()
+	root, err := Parse(testPage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestFind(t *testing.T) {
+	root := parseTestPage(t)
+	tests := []struct {
+		xpath string
+		want  string
+	}{
+		{`//*[@id="main"]/h1`, "Apple iPhone"},
+		{`//h1[contains(@class, "main")]`, "Apple iPhone"},
+		{`//ul/li[2]`, "second"},
+		{`//ul/li[last()]`, "third"},
+		{`(//li)[1]`, "first"},
+		{`//td[.="Brand:"]/following-sibling::td`, "Apple"},
+		{`//td[normalize-space(.)="Color:"]/following-sibling::td[1]`, "Black"},
+		{`//dd/preceding-sibling::dt`, "Condition"},
+		{`//li[starts-with(., "sec")]/following-sibling::li`, "third"},
+		{`//tr[td[.="Color:"]]`, "Color: Black"},
+		{`//li[not(contains(., "i"))]`, "second"},
+		{`//dt/text()`, "Condition"},
+	}
+	for _, tt := range tests {
+		n, err := Find(root, tt.xpath)
+		if err != nil {
+			t.Errorf("Find(%s) error: %v", tt.xpath, err)
+			continue
+		}
+		if got := Text(n); got != tt.want {
+			t.Errorf("Find(%s) = %q, want %q", tt.xpath, got, tt.want)
+		}
+	}
+}
+
+func TestFindAll(t *testing.T) {
+	root := parseTestPage(t)
+	rows, err := FindAll(root, "//tr")
+	if err != nil || len(rows) != 2 {
+		t.Fatalf("FindAll(//tr) = %d rows, %v, want 2", len(rows), err)
+	}
+	// relative to the row
+	cell, err := Find(rows[1], ".//td[2]")
+	if err != nil || Text(cell) != "Black" {
+		t.Errorf("Find(.//td[2]) of the second row = %v, %v, want Black", cell, err)
+	}
+}
+
+func TestFindErrors(t *testing.T) {
+	root := parseTestPage(t)
+	if _, err := Find(root, "//article"); err == nil {
+		t.Errorf("Find() of a missing element, want error")
+	}
+	for _, xpath := range []string{"//li[", "//li[@id=", "//*[unknown-function(.)]", "///"} {
+		if _, err := FindAll(root, xpath); err == nil {
+			t.Errorf("FindAll(%s) of invalid xpath, want error", xpath)
+		}
+	}
+}
+
+func TestAttr(t *testing.T) {
+	root := parseTestPage(t)
+	link, err := Find(root, "//a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := Attr(link, "HREF"); got != "/itm/123?hash=1" {
+		t.Errorf("Attr(href) = %q", got)
+	}
+	if got := Attr(link, "missing"); got != "" {
+		t.Errorf("Attr(missing) = %q, want empty", got)
+	}
+}
+
+func TestText(t *testing.T) {
+	root := parseTestPage(t)
+	want := "Apple iPhone\nLine one\nLine two\nfirst\nsecond\nthird\nBrand: Apple\nColor: Black\nCondition\nNew\nLink"
+	if got := Text(root); got != want {
+		t.Errorf("Text() = %q, want %q", got, want)
+	}
+}
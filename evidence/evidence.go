@@ -0,0 +1,165 @@
+package evidence
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// ManifestVersion is the version of manifest format
+const ManifestVersion = 1
+
+// Artifact is a file of the capture
+type Artifact struct {
+	// Key is the object key in oss, empty if the artifact is not uploaded
+	Key string `json:"key,omitempty"`
+	// Sha256 is the hex SHA-256 of the artifact content
+	Sha256 string `json:"sha256"`
+	// Size is the byte size of the artifact content
+	Size int `json:"size"`
+}
+
+// Manifest describe how and when a screenshot is captured
+type Manifest struct {
+	Version    int       `json:"version"`
+	Url        string    `json:"url"`
+	Channel    string    `json:"channel"`
+	Country    string    `json:"country"`
+	Asin       string    `json:"asin"`
+	PostalCode string    `json:"postalCode,omitempty"`
+	Price      float32   `json:"price"`
+	Status     string    `json:"status"`
+	StartedAt  time.Time `json:"startedAt"`
+	CapturedAt time.Time `json:"capturedAt"`
+	SignedAt   time.Time `json:"signedAt"`
+	Browser    string    `json:"browser"`
+	UserAgent  string    `json:"userAgent"`
+	WorkerHost string    `json:"workerHost"`
+	// Screenshot is the picture published in the result
+	Screenshot Artifact `json:"screenshot"`
+	// Html is the raw page source of the web page
+	Html Artifact `json:"html"`
+	// KeyId identify the public key verifying the signature
+	KeyId string `json:"keyId"`
+}
+
+// NewArtifact make the artifact of content
+func NewArtifact(key string, content []byte) Artifact {
+	sum := sha256.Sum256(content)
+	return Artifact{Key: key, Sha256: hex.EncodeToString(sum[:]), Size: len(content)}
+}
+
+// Matches check the content is the artifact
+func (a Artifact) Matches(content []byte) bool {
+	sum := sha256.Sum256(content)
+	return a.Sha256 == hex.EncodeToString(sum[:]) && a.Size == len(content)
+}
+
+// KeyId is the first 16 hex characters of SHA-256 of the public key
+func KeyId(publicKey ed25519.PublicKey) string {
+	sum := sha256.Sum256(publicKey)
+	return hex.EncodeToString(sum[:8])
+}
+
+// Sign serialize the manifest and sign the serialized bytes
+//  @return []byte the manifest json, the exact bytes are signed
+//  @return []byte the base64 Ed25519 signature
+func Sign(manifest Manifest, privateKey ed25519.PrivateKey) ([]byte, []byte, error) {
+	manifest.Version = ManifestVersion
+	manifest.SignedAt = time.Now().UTC()
+	manifest.KeyId = KeyId(privateKey.Public().(ed25519.PublicKey))
+
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, nil, err
+	}
+	signature := ed25519.Sign(privateKey, content)
+	return content, []byte(base64.StdEncoding.EncodeToString(signature)), nil
+}
+
+// Verify check the signature of the manifest json
+//  @return *Manifest the manifest parsed, only if the signature is valid
+func Verify(content []byte, signature []byte, publicKey ed25519.PublicKey) (*Manifest, error) {
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil {
+		return nil, fmt.Errorf("signature is not base64: %v", err)
+	}
+	if !ed25519.Verify(publicKey, content, sig) {
+		return nil, errors.New("signature does not match the manifest")
+	}
+
+	manifest := new(Manifest)
+	if err = json.Unmarshal(content, manifest); err != nil {
+		return nil, err
+	}
+	if manifest.KeyId != KeyId(publicKey) {
+		return nil, fmt.Errorf("manifest is signed by key %s, not %s", manifest.KeyId, KeyId(publicKey))
+	}
+	return manifest, nil
+}
+
+// readPEM read the first pem block of file
+func readPEM(path string) (*pem.Block, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a pem file", path)
+	}
+	return block, nil
+}
+
+// LoadPrivateKey load the Ed25519 private key from a PKCS #8 pem file,
+// exp: openssl genpkey -algorithm ed25519 -out ed25519.pem
+func LoadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an Ed25519 private key", path)
+	}
+	return privateKey, nil
+}
+
+// LoadPublicKey load the Ed25519 public key from a PKIX pem file, a private key file is accepted too,
+// exp: openssl pkey -in ed25519.pem -pubout -out ed25519.pub.pem
+func LoadPublicKey(path string) (ed25519.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	if block.Type == "PRIVATE KEY" {
+		privateKey, err := LoadPrivateKey(path)
+		if err != nil {
+			return nil, err
+		}
+		return privateKey.Public().(ed25519.PublicKey), nil
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an Ed25519 public key", path)
+	}
+	return publicKey, nil
+}
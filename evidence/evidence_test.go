@@ -0,0 +1,144 @@
+package evidence
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newKey(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return publicKey, privateKey
+}
+
+// writePEM write the der bytes as a pem file in the temp dir of the test
+func writePEM(t *testing.T, name string, blockType string, der []byte) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestKeyId(t *testing.T) {
+	publicKey, _ := newKey(t)
+	another, _ := newKey(t)
+	id := KeyId(publicKey)
+	if len(id) != 16 {
+		t.Errorf("KeyId() = %s, want 16 hex characters", id)
+	}
+	if id != KeyId(publicKey) || id == KeyId(another) {
+		t.Errorf("KeyId() is not the identity of the key")
+	}
+}
+
+func TestArtifactMatches(t *testing.T) {
+	artifact := NewArtifact("a.png", []byte("picture"))
+	if artifact.Key != "a.png" || artifact.Size != 7 {
+		t.Errorf("NewArtifact() = %+v", artifact)
+	}
+	if !artifact.Matches([]byte("picture")) {
+		t.Errorf("Matches() of the same content = false")
+	}
+	if artifact.Matches([]byte("pictures")) || artifact.Matches(nil) {
+		t.Errorf("Matches() of another content = true")
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	publicKey, privateKey := newKey(t)
+	capturedAt := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	content, signature, err := Sign(Manifest{Url: "https://www.ebay.com/itm/1", Asin: "1", CapturedAt: capturedAt,
+		Screenshot: NewArtifact("1.png", []byte("picture"))}, privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, err := Verify(content, signature, publicKey)
+	if err != nil {
+		t.Fatalf("Verify() error: %v", err)
+	}
+	if manifest.Version != ManifestVersion || manifest.KeyId != KeyId(publicKey) || manifest.SignedAt.IsZero() {
+		t.Errorf("Verify() = %+v, want the version, key id and signing time", manifest)
+	}
+	if manifest.Url != "https://www.ebay.com/itm/1" || !manifest.CapturedAt.Equal(capturedAt) || !manifest.Screenshot.Matches([]byte("picture")) {
+		t.Errorf("Verify() = %+v, want the signed fields", manifest)
+	}
+	// the signature file may end with a newline
+	if _, err = Verify(content, append(signature, '\n'), publicKey); err != nil {
+		t.Errorf("Verify() of the signature with a newline error: %v", err)
+	}
+
+	tampered := bytes.Replace(content, []byte("itm/1"), []byte("itm/2"), 1)
+	if _, err = Verify(tampered, signature, publicKey); err == nil {
+		t.Errorf("Verify() of a tampered manifest, want error")
+	}
+	another, _ := newKey(t)
+	if _, err = Verify(content, signature, another); err == nil {
+		t.Errorf("Verify() by another key, want error")
+	}
+	if _, err = Verify(content, []byte("not base64!"), publicKey); err == nil {
+		t.Errorf("Verify() of a signature not base64, want error")
+	}
+}
+
+func TestLoadKeys(t *testing.T) {
+	publicKey, privateKey := newKey(t)
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privatePath := writePEM(t, "ed25519.pem", "PRIVATE KEY", der)
+	der, err = x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicPath := writePEM(t, "ed25519.pub.pem", "PUBLIC KEY", der)
+
+	loaded, err := LoadPrivateKey(privatePath)
+	if err != nil || !loaded.Equal(privateKey) {
+		t.Errorf("LoadPrivateKey() = %v, want the private key", err)
+	}
+	for _, path := range []string{publicPath, privatePath} {
+		loadedPublic, err := LoadPublicKey(path)
+		if err != nil || !loadedPublic.Equal(publicKey) {
+			t.Errorf("LoadPublicKey(%s) = %v, want the public key", filepath.Base(path), err)
+		}
+	}
+
+	// not an Ed25519 key
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ = x509.MarshalPKCS8PrivateKey(ecKey)
+	ecPrivatePath := writePEM(t, "ec.pem", "PRIVATE KEY", der)
+	der, _ = x509.MarshalPKIXPublicKey(ecKey.Public())
+	ecPublicPath := writePEM(t, "ec.pub.pem", "PUBLIC KEY", der)
+	if _, err = LoadPrivateKey(ecPrivatePath); err == nil {
+		t.Errorf("LoadPrivateKey() of an ecdsa key, want error")
+	}
+	if _, err = LoadPublicKey(ecPublicPath); err == nil {
+		t.Errorf("LoadPublicKey() of an ecdsa key, want error")
+	}
+
+	notPEM := filepath.Join(t.TempDir(), "key.txt")
+	_ = os.WriteFile(notPEM, []byte("not a key"), 0600)
+	if _, err = LoadPublicKey(notPEM); err == nil {
+		t.Errorf("LoadPublicKey() of a file not pem, want error")
+	}
+	if _, err = LoadPrivateKey(filepath.Join(t.TempDir(), "missing.pem")); err == nil {
+		t.Errorf("LoadPrivateKey() of a missing file, want error")
+	}
+}
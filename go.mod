@@ -4,16 +4,22 @@ go 1.18
 
 require (
 	github.com/aliyun/aliyun-oss-go-sdk v2.2.4+incompatible
+	github.com/antchfx/htmlquery v1.3.5
 	github.com/rabbitmq/amqp091-go v1.5.0
 	github.com/tebeka/selenium v0.9.9
+	golang.org/x/image v0.18.0
+	golang.org/x/net v0.33.0
 	gopkg.in/ini.v1 v1.66.6
 )
 
 require (
+	github.com/antchfx/xpath v1.3.5 // indirect
 	github.com/baiyubin/aliyun-sts-go-sdk v0.0.0-20180326062324-cfa1a18b161f // indirect
 	github.com/blang/semver v3.5.1+incompatible // indirect
+	github.com/golang/groupcache v0.0.0-20210331224755-41bb18bfe9da // indirect
 	github.com/satori/go.uuid v1.2.0 // indirect
 	github.com/stretchr/testify v1.7.5 // indirect
+	golang.org/x/text v0.21.0 // indirect
 	golang.org/x/time v0.0.0-20190308202827-9d24e82272b4 // indirect
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
 )
@@ -0,0 +1,130 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"y-clouds.com/tarantula/oss"
+)
+
+const defaultPrefix = "history/"
+
+// Record is the last capture of an item
+type Record struct {
+	Channel    string    `json:"channel"`
+	Country    string    `json:"country"`
+	Asin       string    `json:"asin"`
+	Screenshot string    `json:"screenshot"`
+	Hash       string    `json:"hash"`
+	Price      float32   `json:"price"`
+	CapturedAt time.Time `json:"capturedAt"`
+	// Reference is the png screenshot without watermark compared by the diff, empty if it is the screenshot
+	Reference string `json:"reference,omitempty"`
+}
+
+// Store keeps the last capture per channel/country/asin as json objects in oss,
+// so that every worker compares with the same previous capture
+type Store struct {
+	// Enabled indicates whether to compare with the last capture
+	Enabled bool
+	// Prefix is the prefix of record objects, default: history/
+	Prefix string
+	// Threshold is the visual change score in [0, 1], a screenshot differs beyond the threshold is changed
+	Threshold float64
+	// UploadDiff indicates whether to upload a diff picture next to the screenshot when the item is changed
+	UploadDiff bool
+	// Oss is where the records are stored
+	Oss *oss.AliOss `ini:"-"`
+}
+
+// recordKey exp: history/ebay/US/123456.json
+func (s Store) recordKey(channel string, country string, asin string) string {
+	prefix := s.Prefix
+	if prefix == "" {
+		prefix = defaultPrefix
+	}
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	escape := func(v string) string { return strings.ReplaceAll(v, "/", "-") }
+	return fmt.Sprintf("%s%s/%s/%s.json", prefix, escape(channel), escape(country), escape(asin))
+}
+
+// Last get the last capture of the item
+//  @return *Record nil if the item has never been captured
+func (s Store) Last(channel string, country string, asin string) (*Record, error) {
+	key := s.recordKey(channel, country, asin)
+	exist, err := s.Oss.IsObjectExist(key)
+	if err != nil || !exist {
+		return nil, err
+	}
+
+	content, err := s.Oss.GetBytesFromOSS(key)
+	if err != nil {
+		return nil, err
+	}
+	record := new(Record)
+	if err = json.Unmarshal(content, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// Save replace the last capture of the item
+func (s Store) Save(record Record) error {
+	content, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if !s.Oss.PutBytesOnOSS(s.recordKey(record.Channel, record.Country, record.Asin), content) {
+		return fmt.Errorf("history.save %s/%s/%s failed", record.Channel, record.Country, record.Asin)
+	}
+	return nil
+}
+
+// ListRecord is the items found in the last capture of a seller's item list or search results
+type ListRecord struct {
+	Channel    string    `json:"channel"`
+	Country    string    `json:"country"`
+	List       string    `json:"list"`
+	ItemIds    []string  `json:"itemIds"`
+	CapturedAt time.Time `json:"capturedAt"`
+}
+
+// listKey exp: history/ebay/US/seller-xxx.items.json
+func (s Store) listKey(channel string, country string, list string) string {
+	return strings.TrimSuffix(s.recordKey(channel, country, list), ".json") + ".items.json"
+}
+
+// LastList get the items of the last capture of the list
+//  @return *ListRecord nil if the list has never been captured
+func (s Store) LastList(channel string, country string, list string) (*ListRecord, error) {
+	key := s.listKey(channel, country, list)
+	exist, err := s.Oss.IsObjectExist(key)
+	if err != nil || !exist {
+		return nil, err
+	}
+
+	content, err := s.Oss.GetBytesFromOSS(key)
+	if err != nil {
+		return nil, err
+	}
+	record := new(ListRecord)
+	if err = json.Unmarshal(content, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// SaveList replace the items of the last capture of the list
+func (s Store) SaveList(record ListRecord) error {
+	content, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if !s.Oss.PutBytesOnOSS(s.listKey(record.Channel, record.Country, record.List), content) {
+		return fmt.Errorf("history.save_list %s/%s/%s failed", record.Channel, record.Country, record.List)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package history
+
+import (
+	"reflect"
+	"testing"
+	"time"
+	"y-clouds.com/tarantula/oss"
+	"y-clouds.com/tarantula/oss/osstest"
+)
+
+func newTestStore(t *testing.T, prefix string) (Store, *osstest.Bucket) {
+	bucket := osstest.NewBucket(t)
+	aliOss := &oss.AliOss{Endpoint: bucket.Endpoint, AccessID: "id", AccessKey: "key", BucketName: bucket.Name}
+	return Store{Enabled: true, Prefix: prefix, Oss: aliOss}, bucket
+}
+
+func TestRecordKey(t *testing.T) {
+	tests := []struct {
+		prefix string
+		want   string
+	}{
+		{"", "history/ebay/US/123456.json"},
+		{"last", "last/ebay/US/123456.json"},
+		{"last/", "last/ebay/US/123456.json"},
+	}
+	for _, tt := range tests {
+		if got := (Store{Prefix: tt.prefix}).recordKey("ebay", "US", "123456"); got != tt.want {
+			t.Errorf("recordKey() with prefix %q = %s, want %s", tt.prefix, got, tt.want)
+		}
+	}
+	// a generic page is named by its url, the slashes do not make directories
+	if got := (Store{}).recordKey("generic", "US", "example.com/shop/1"); got != "history/generic/US/example.com-shop-1.json" {
+		t.Errorf("recordKey() of a url name = %s", got)
+	}
+	if got := (Store{}).listKey("ebay", "US", "seller-shop"); got != "history/ebay/US/seller-shop.items.json" {
+		t.Errorf("listKey() = %s", got)
+	}
+}
+
+func TestRecordReadAfterWrite(t *testing.T) {
+	store, bucket := newTestStore(t, "")
+	record := Record{
+		Channel:    "ebay",
+		Country:    "US",
+		Asin:       "123456",
+		Screenshot: "ebay/US/2022/08/01/123456/a.png",
+		Hash:       "0f0f0f0f0f0f0f0f",
+		Price:      12.5,
+		CapturedAt: time.Date(2022, 8, 1, 10, 0, 0, 0, time.UTC),
+		Reference:  "ebay/US/2022/08/01/123456/a-raw.png",
+	}
+	if err := store.Save(record); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := bucket.Object("history/ebay/US/123456.json"); !ok {
+		t.Errorf("the record is not stored at history/ebay/US/123456.json")
+	}
+	last, err := store.Last("ebay", "US", "123456")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last == nil || !reflect.DeepEqual(*last, record) {
+		t.Errorf("Last() = %+v, want %+v", last, record)
+	}
+
+	// a later capture replaces the record
+	record.Price, record.Reference = 13, ""
+	if err = store.Save(record); err != nil {
+		t.Fatal(err)
+	}
+	if last, err = store.Last("ebay", "US", "123456"); err != nil || last.Price != 13 || last.Reference != "" {
+		t.Errorf("Last() after the second save = %+v, %v, want price 13 without reference", last, err)
+	}
+}
+
+func TestRecordMissing(t *testing.T) {
+	store, bucket := newTestStore(t, "last/")
+	last, err := store.Last("ebay", "US", "never-captured")
+	if err != nil || last != nil {
+		t.Errorf("Last() of an item never captured = %+v, %v, want nil, nil", last, err)
+	}
+	list, err := store.LastList("ebay", "US", "seller-shop")
+	if err != nil || list != nil {
+		t.Errorf("LastList() of a list never captured = %+v, %v, want nil, nil", list, err)
+	}
+
+	// a corrupted record is an error, not a first capture
+	bucket.Put("last/ebay/US/broken.json", []byte("{not json"))
+	if _, err = store.Last("ebay", "US", "broken"); err == nil {
+		t.Errorf("Last() of a corrupted record, want error")
+	}
+}
+
+func TestListRecordReadAfterWrite(t *testing.T) {
+	store, _ := newTestStore(t, "")
+	record := ListRecord{
+		Channel:    "ebay",
+		Country:    "US",
+		List:       "seller-shop",
+		ItemIds:    []string{"223456789012", "323456789012"},
+		CapturedAt: time.Date(2022, 8, 1, 10, 0, 0, 0, time.UTC),
+	}
+	if err := store.SaveList(record); err != nil {
+		t.Fatal(err)
+	}
+	last, err := store.LastList("ebay", "US", "seller-shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last == nil || !reflect.DeepEqual(*last, record) {
+		t.Errorf("LastList() = %+v, want %+v", last, record)
+	}
+	// the list and the item of the same name are different records
+	if item, err := store.Last("ebay", "US", "seller-shop"); err != nil || item != nil {
+		t.Errorf("Last() of the list name = %+v, %v, want nil", item, err)
+	}
+}
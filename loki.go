@@ -1,15 +1,26 @@
 package main
 
 import (
+	"bytes"
+	"crypto/ed25519"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"golang.org/x/image/draw"
 	"gopkg.in/ini.v1"
+	"image/color"
 	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 	"y-clouds.com/tarantula/capture"
+	"y-clouds.com/tarantula/evidence"
+	"y-clouds.com/tarantula/history"
 	"y-clouds.com/tarantula/middleware"
 	"y-clouds.com/tarantula/oss"
+	"y-clouds.com/tarantula/tools"
 )
 
 type Rabbit struct {
@@ -17,6 +28,83 @@ type Rabbit struct {
 	Exchange string
 }
 
+// Watermark is the provenance banner rendered onto the screenshot
+type Watermark struct {
+	Enabled bool
+	// top / bottom
+	Position string
+	// pixel size of the bundled font
+	Scale int
+	// color like #rrggbb or #rrggbbaa
+	Background string
+	Foreground string
+	// WorkerID identify the worker in the banner, default is the hostname
+	WorkerID string
+
+	// background and foreground is the parsed colors, nil is the default
+	background color.Color
+	foreground color.Color
+}
+
+// parseColors parse the background and foreground, an empty color is the default
+func (w *Watermark) parseColors() (err error) {
+	if w.Background != "" {
+		if w.background, err = tools.ParseHexColor(w.Background); err != nil {
+			return fmt.Errorf("Background %w", err)
+		}
+	}
+	if w.Foreground != "" {
+		if w.foreground, err = tools.ParseHexColor(w.Foreground); err != nil {
+			return fmt.Errorf("Foreground %w", err)
+		}
+	}
+	return nil
+}
+
+// PageSource is the configuration of keeping the page source next to the screenshot
+type PageSource struct {
+	Enabled bool
+	// Frame indicates whether to keep the source of main iframe too, exp: the description of ebay item
+	Frame bool
+}
+
+// Debug is the configuration of uploading the page state when the capture fails
+type Debug struct {
+	Enabled bool
+	// Prefix is the prefix of debug artifacts, default: debug/
+	Prefix string
+}
+
+// Steps is the configuration of the interaction steps of requests
+type Steps struct {
+	// AllowScript indicates whether a request may execute javascript by a script step,
+	// the steps of site definitions are always allowed
+	AllowScript bool
+	// MaxWait is the longest timeout and sleep of a step, a longer step is rejected, default 1m
+	MaxWait time.Duration
+}
+
+// Evidence is the signing configuration of capture manifests
+type Evidence struct {
+	Enabled bool
+	// PrivateKey is the path of Ed25519 private key pem file, used to sign manifests
+	PrivateKey string
+	// PublicKey is the path of Ed25519 public key pem file, used by the verify command
+	PublicKey string
+
+	privateKey ed25519.PrivateKey
+}
+
+// Thumbnail is the configuration of the downscaled screenshots uploaded next to the screenshot
+type Thumbnail struct {
+	// Widths is the width of every thumbnail, none disables thumbnails
+	Widths []int
+	// Filter is the resampling filter: nearest, approxbilinear, bilinear or catmullrom
+	Filter string
+
+	filter draw.Interpolator
+}
+
 var confFile = flag.String("c", "./conf-local.ini", "Snapshot tool configuration file.")
 
 // AppConf is the config of app
@@ -27,13 +115,49 @@ type AppConf struct {
 	AmpqConf     *Rabbit
 	SeleniumConf *capture.Selenium
 	OssConf      *oss.AliOss
+	HistoryConf  *history.Store
+	Watermark    *Watermark
+	EvidenceConf *Evidence
+	PageSource   *PageSource
+	DebugConf    *Debug
+	StepsConf    *Steps
+	// BlockRules is the block page rule per site, the key is lower-case channel
+	BlockRules map[string]capture.BlockRule
+	// BlockCooldown is the cooldown of sites without a rule
+	BlockCooldown time.Duration
+	// ListingRules is the listing state rule per site, the key is lower-case channel
+	ListingRules map[string]capture.ListingRule
+	// OverlayRules is the overlay dismissal rule per site, the key is lower-case channel
+	OverlayRules map[string]capture.OverlayRule
+	// LocationRules is the delivery location rule per site, the key is lower-case channel
+	LocationRules map[string]capture.LocationRule
+	// ProxyPool is the proxies per country of the request
+	ProxyPool *capture.ProxyPool
+	// Layout is how the captured regions are spliced
+	Layout capture.Layout
+	// Outputs is the encoding of screenshots per site, the key is lower-case channel, the empty key is the default
+	Outputs map[string]tools.ImageFormat
+	// ThumbnailConf is the thumbnails of screenshots, encoded as the screenshot
+	ThumbnailConf *Thumbnail
+	// Sites is the site definitions by lower-case channel, captured by the generic capturer
+	Sites map[string]*capture.SiteDefinition
+	// Profiles is the browser profile per site or site/country, the key is lower-case {channel} or {channel}.{country},
+	// the empty key is the default profile
+	Profiles map[string]capture.Profile
 }
 
 var appConf = new(AppConf)
 
+// blockTracker count the blocks per site, and back off the blocked sites
+var blockTracker = capture.NewBlockTracker()
+
+// confLoadOptions is how the config file is read, shadow keys are the repeated keys of a list, exp: the xpath of overlays,
+// # and ; are not inline comments, so that the colors like #ffffff are kept
+var confLoadOptions = ini.LoadOptions{AllowShadows: true, IgnoreInlineComment: true}
+
 // setAppConf is used to set config params of the app
 func setAppConf() {
-	cfg, err := ini.Load(*confFile)
+	cfg, err := ini.LoadSources(confLoadOptions, *confFile)
 
 	if err != nil {
 		log.Fatalf("Fail to read file: %v", err)
@@ -70,44 +194,788 @@ func setAppConf() {
 		log.Fatalf("Missing Ali oss configurationparameters: %v", err)
 	}
 	appConf.OssConf = aliOss
+
+	// history conf
+	historyConf := &history.Store{Threshold: 0.1}
+	err = cfg.Section("History").MapTo(historyConf)
+	if err != nil {
+		log.Fatalf("Missing history configuration parameters: %v", err)
+	}
+	historyConf.Oss = aliOss
+	appConf.HistoryConf = historyConf
+
+	// watermark conf
+	watermark := new(Watermark)
+	err = cfg.Section("Watermark").MapTo(watermark)
+	if err != nil {
+		log.Fatalf("Missing watermark configuration parameters: %v", err)
+	}
+	if watermark.WorkerID == "" {
+		watermark.WorkerID, _ = os.Hostname()
+	}
+	if err = watermark.parseColors(); err != nil {
+		log.Fatalf("Invalid watermark configuration: %v", err)
+	}
+	appConf.Watermark = watermark
+
+	// evidence conf
+	evidenceConf := new(Evidence)
+	err = cfg.Section("Evidence").MapTo(evidenceConf)
+	if err != nil {
+		log.Fatalf("Missing evidence configuration parameters: %v", err)
+	}
+	if evidenceConf.Enabled {
+		evidenceConf.privateKey, err = evidence.LoadPrivateKey(evidenceConf.PrivateKey)
+		if err != nil {
+			log.Fatalf("Fail to load evidence private key: %v", err)
+		}
+	}
+	appConf.EvidenceConf = evidenceConf
+
+	// page source conf
+	pageSource := new(PageSource)
+	err = cfg.Section("PageSource").MapTo(pageSource)
+	if err != nil {
+		log.Fatalf("Missing page source configuration parameters: %v", err)
+	}
+	appConf.PageSource = pageSource
+
+	// debug conf
+	debugConf := new(Debug)
+	err = cfg.Section("Debug").MapTo(debugConf)
+	if err != nil {
+		log.Fatalf("Missing debug configuration parameters: %v", err)
+	}
+	appConf.DebugConf = debugConf
+
+	// steps conf
+	stepsConf := &Steps{MaxWait: capture.MaxStepWait}
+	err = cfg.Section("Steps").MapTo(stepsConf)
+	if err != nil {
+		log.Fatalf("Missing steps configuration parameters: %v", err)
+	}
+	if stepsConf.MaxWait <= 0 {
+		log.Fatalf("Invalid steps configuration: MaxWait %v", stepsConf.MaxWait)
+	}
+	// the steps of site definitions loaded below are checked against it too
+	capture.MaxStepWait = stepsConf.MaxWait
+	appConf.StepsConf = stepsConf
+
+	// layout conf
+	err = cfg.Section("Layout").MapTo(&appConf.Layout)
+	if err == nil {
+		err = appConf.Layout.Validate()
+	}
+	if err != nil {
+		log.Fatalf("Invalid layout configuration: %v", err)
+	}
+
+	// output conf, [Output.{channel}] inherit keys of [Output]
+	appConf.Outputs = map[string]tools.ImageFormat{}
+	for _, section := range append([]*ini.Section{cfg.Section("Output")}, cfg.Section("Output").ChildSections()...) {
+		var format tools.ImageFormat
+		err = section.MapTo(&format)
+		if err == nil {
+			err = format.Validate()
+		}
+		if err != nil {
+			log.Fatalf("Invalid output configuration of %s: %v", section.Name(), err)
+		}
+		name := strings.TrimPrefix(strings.TrimPrefix(section.Name(), "Output"), ".")
+		appConf.Outputs[strings.ToLower(name)] = format
+	}
+
+	// thumbnail conf
+	thumbnailConf := new(Thumbnail)
+	err = cfg.Section("Thumbnail").MapTo(thumbnailConf)
+	if err != nil {
+		log.Fatalf("Missing thumbnail configuration parameters: %v", err)
+	}
+	thumbnailConf.filter, err = tools.ResampleFilterByName(thumbnailConf.Filter)
+	if err != nil {
+		log.Fatalf("Invalid thumbnail configuration: %v", err)
+	}
+	appConf.ThumbnailConf = thumbnailConf
+
+	// site definitions, their rule sections replace the default rules of the sites, overridden by the sections of config
+	appConf.Sites = map[string]*capture.SiteDefinition{}
+	if dir := cfg.Section("Sites").Key("Dir").String(); dir != "" {
+		appConf.Sites, err = capture.LoadSiteDefinitions(dir)
+		if err != nil {
+			log.Fatalf("Fail to load site definitions: %v", err)
+		}
+	}
+	for name, definition := range appConf.Sites {
+		definition.OverrideDefaultRules()
+		log.Printf("Site definition %s loaded, rules: %v", name, definition.RuleSections)
+	}
+
+	// block conf, [Block.{channel}] override the default rule of the site, and inherit keys of [Block]
+	appConf.BlockCooldown = cfg.Section("Block").Key("Cooldown").MustDuration(0)
+	appConf.BlockRules = map[string]capture.BlockRule{}
+	for site, rule := range capture.DefaultBlockRules {
+		if rule.Cooldown == 0 {
+			rule.Cooldown = appConf.BlockCooldown
+		}
+		appConf.BlockRules[site] = rule
+	}
+	for _, section := range cfg.Section("Block").ChildSections() {
+		site := strings.ToLower(strings.TrimPrefix(section.Name(), "Block."))
+		rule := appConf.BlockRules[site]
+		err = section.MapTo(&rule)
+		if err != nil {
+			log.Fatalf("Missing block configuration parameters of %s: %v", site, err)
+		}
+		appConf.BlockRules[site] = rule
+	}
+
+	// listing conf, [Listing.{channel}.{NotFound|Ended|OutOfStock}] override the default rule of the site
+	appConf.ListingRules = map[string]capture.ListingRule{}
+	for site, rule := range capture.DefaultListingRules {
+		appConf.ListingRules[site] = rule
+	}
+	for _, section := range cfg.Section("Listing").ChildSections() {
+		// exp: Listing.ebay.Ended
+		names := strings.SplitN(section.Name(), ".", 3)
+		if len(names) != 3 {
+			continue
+		}
+		site := strings.ToLower(names[1])
+		rule := appConf.ListingRules[site]
+		states := map[string]*capture.PageRule{"NotFound": &rule.NotFound, "Ended": &rule.Ended, "OutOfStock": &rule.OutOfStock}
+		pageRule, ok := states[names[2]]
+		if !ok {
+			log.Fatalf("Unknown listing state: %s", section.Name())
+		}
+		err = section.MapTo(pageRule)
+		if err != nil {
+			log.Fatalf("Missing listing configuration parameters of %s: %v", section.Name(), err)
+		}
+		appConf.ListingRules[site] = rule
+	}
+
+	// overlay conf, [Overlay.{channel}] override the default rule of the site, and inherit keys of [Overlay]
+	hideFixed := cfg.Section("Overlay").Key("HideFixed").MustBool(false)
+	appConf.OverlayRules = map[string]capture.OverlayRule{}
+	for site, rule := range capture.DefaultOverlayRules {
+		rule.HideFixed = rule.HideFixed || hideFixed
+		appConf.OverlayRules[site] = rule
+	}
+	for _, section := range cfg.Section("Overlay").ChildSections() {
+		site := strings.ToLower(strings.TrimPrefix(section.Name(), "Overlay."))
+		rule, ok := appConf.OverlayRules[site]
+		if !ok {
+			rule.HideFixed = hideFixed
+		}
+		err = section.MapTo(&rule)
+		if err != nil {
+			log.Fatalf("Missing overlay configuration parameters of %s: %v", site, err)
+		}
+		appConf.OverlayRules[site] = rule
+	}
+
+	// location conf, [Location.{channel}] override the default rule of the site, and inherit keys of [Location]
+	locationTimeout := cfg.Section("Location").Key("Timeout").MustDuration(0)
+	appConf.LocationRules = map[string]capture.LocationRule{}
+	for site, rule := range capture.DefaultLocationRules {
+		if rule.Timeout == 0 {
+			rule.Timeout = locationTimeout
+		}
+		appConf.LocationRules[site] = rule
+	}
+	for _, section := range cfg.Section("Location").ChildSections() {
+		site := strings.ToLower(strings.TrimPrefix(section.Name(), "Location."))
+		rule, ok := appConf.LocationRules[site]
+		if !ok {
+			rule.Timeout = locationTimeout
+		}
+		err = section.MapTo(&rule)
+		if err != nil {
+			log.Fatalf("Missing location configuration parameters of %s: %v", site, err)
+		}
+		appConf.LocationRules[site] = rule
+	}
+
+	// proxy conf, [Proxy.{country}] list the proxies of the country by repeated Url keys
+	proxies := map[string][]string{}
+	for _, section := range cfg.Section("Proxy").ChildSections() {
+		country := strings.TrimPrefix(section.Name(), "Proxy.")
+		if section.HasKey("Url") {
+			proxies[country] = section.Key("Url").ValueWithShadows()
+		}
+	}
+	quarantine := cfg.Section("Proxy").Key("Quarantine").MustDuration(5 * time.Minute)
+	appConf.ProxyPool, err = capture.NewProxyPool(proxies, quarantine)
+	if err != nil {
+		log.Fatalf("Invalid proxy configuration: %v", err)
+	}
+
+	// profile conf, [Profile.{channel}] and [Profile.{channel}.{country}] inherit keys of their parent sections
+	appConf.Profiles = map[string]capture.Profile{}
+	for _, section := range append([]*ini.Section{cfg.Section("Profile")}, cfg.Section("Profile").ChildSections()...) {
+		profile := capture.DefaultProfile
+		err = section.MapTo(&profile)
+		if err != nil {
+			log.Fatalf("Missing profile configuration parameters of %s: %v", section.Name(), err)
+		}
+		name := strings.TrimPrefix(strings.TrimPrefix(section.Name(), "Profile"), ".")
+		appConf.Profiles[strings.ToLower(name)] = profile
+	}
+}
+
+// siteRules get the rules of the site applied by every capturer
+func siteRules(channel string) capture.Site {
+	site := strings.ToLower(channel)
+	return capture.Site{
+		BlockRule:    blockRule(channel),
+		ListingRule:  appConf.ListingRules[site],
+		OverlayRule:  appConf.OverlayRules[site],
+		LocationRule: appConf.LocationRules[site],
+	}
+}
+
+// browserProfile get the browser profile of the channel and country
+func browserProfile(channel string, country string) capture.Profile {
+	for _, name := range []string{channel + "." + country, channel, ""} {
+		if profile, ok := appConf.Profiles[strings.ToLower(name)]; ok {
+			return profile
+		}
+	}
+	return capture.DefaultProfile
+}
+
+// outputFormat get the encoding of screenshots of the site
+func outputFormat(channel string) tools.ImageFormat {
+	if format, ok := appConf.Outputs[strings.ToLower(channel)]; ok {
+		return format
+	}
+	return appConf.Outputs[""]
+}
+
+// blockRule get the block page rule of the channel
+func blockRule(channel string) capture.BlockRule {
+	if rule, ok := appConf.BlockRules[strings.ToLower(channel)]; ok {
+		return rule
+	}
+	return capture.BlockRule{Cooldown: appConf.BlockCooldown}
 }
 
 // GetEbayWebScreenshots is start to get tarantula
-func getEbayWebScreenshots(asin string) (float32, []byte, string) {
-	var seleniumConf = appConf.SeleniumConf
+//  @param proxy the proxy of browser, nil to connect directly
+func getEbayWebScreenshots(param capture.ScreenshotsParam, proxy *capture.Proxy) *capture.Capture {
+	var seleniumConf = *appConf.SeleniumConf
+	seleniumConf.Proxy = proxy
+	seleniumConf.Profile = browserProfile(param.Channel, param.Country)
 	ebay := &capture.Ebay{
-		Asin:       asin,
-		DriverPath: seleniumConf.DriverPath,
-		Port:       seleniumConf.Port,
+		Asin:     param.Asin,
+		Selenium: seleniumConf,
+		Site:     siteRules(param.Channel),
+		// the frame source is kept only if it is uploaded
+		DescriptionSource:   appConf.PageSource.Enabled && appConf.PageSource.Frame,
+		Debug:               appConf.DebugConf.Enabled,
+		PostalCode:          param.PostalCode,
+		Variation:           param.Variation,
+		EnumerateVariations: param.EnumerateVariations,
+		Steps:               param.Steps,
+		Layout:              appConf.Layout,
 	}
 
 	return ebay.WebScreenshots()
 }
 
-// uploadScreenshots Upload images to oss
-func uploadScreenshots(imageName string, imageBytes []byte) {
+// getGenericWebScreenshots capture the regions of an arbitrary page
+//  @param proxy the proxy of browser, nil to connect directly
+func getGenericWebScreenshots(param capture.ScreenshotsParam, proxy *capture.Proxy) *capture.Capture {
+	var seleniumConf = *appConf.SeleniumConf
+	seleniumConf.Proxy = proxy
+	seleniumConf.Profile = browserProfile(param.Channel, param.Country)
+	generic := &capture.Generic{
+		PageUrl:    param.Url,
+		Selectors:  param.Selectors,
+		PostalCode: param.PostalCode,
+		Steps:      param.Steps,
+		Captions:   param.Captions,
+		Layout:     appConf.Layout,
+		Selenium:   seleniumConf,
+		Site:       siteRules(param.Channel),
+		Debug:      appConf.DebugConf.Enabled,
+	}
+	if param.PriceSelector != "" {
+		generic.PriceSelectors = []string{param.PriceSelector}
+	}
+
+	return generic.WebScreenshots()
+}
+
+// getDefinedWebScreenshots capture an item of the site described by a site definition
+//  @param proxy the proxy of browser, nil to connect directly
+func getDefinedWebScreenshots(definition *capture.SiteDefinition, param capture.ScreenshotsParam, proxy *capture.Proxy) *capture.Capture {
+	pageUrl := param.Url
+	if pageUrl == "" {
+		var err error
+		if pageUrl, err = definition.PageUrl(param.Country, param.Asin); err != nil {
+			log.Println(err)
+			return &capture.Capture{Status: string(capture.PAGE_ERROR), Reason: err.Error()}
+		}
+	}
+
+	var seleniumConf = *appConf.SeleniumConf
+	seleniumConf.Proxy = proxy
+	seleniumConf.Profile = browserProfile(param.Channel, param.Country)
+	generic := &capture.Generic{
+		PageUrl:        pageUrl,
+		Selectors:      definition.Regions,
+		PriceSelectors: definition.PriceSelectors,
+		Fields:         definition.Fields,
+		PostalCode:     param.PostalCode,
+		Steps:          append(append([]capture.Step{}, definition.Steps...), param.Steps...),
+		Captions:       definition.Captions,
+		Layout:         appConf.Layout,
+		Selenium:       seleniumConf,
+		Site:           siteRules(param.Channel),
+		Debug:          appConf.DebugConf.Enabled,
+	}
+	if definition.Layout != nil {
+		generic.Layout = *definition.Layout
+	}
+
+	return generic.WebScreenshots()
+}
+
+// urlName is the name of a page used in place of asin, the host and a hash of url, exp: example.com-1a2b3c4d5e6f
+func urlName(pageUrl string) string {
+	host := "page"
+	if u, err := url.Parse(pageUrl); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	return host + "-" + oss.ContentHash([]byte(pageUrl))[:12]
+}
+
+// getEbayListScreenshots capture the result pages of a seller's item list or search results
+//  @param proxy the proxy of browser, nil to connect directly
+func getEbayListScreenshots(param capture.ScreenshotsParam, proxy *capture.Proxy) *capture.Capture {
+	var seleniumConf = *appConf.SeleniumConf
+	seleniumConf.Proxy = proxy
+	seleniumConf.Profile = browserProfile(param.Channel, param.Country)
+	list := &capture.EbayList{
+		Type:     param.Type,
+		Seller:   param.Seller,
+		Query:    param.Query,
+		MaxPages: param.MaxPages,
+		Selenium: seleniumConf,
+		Site:     siteRules(param.Channel),
+		Debug:    appConf.DebugConf.Enabled,
+	}
+
+	return list.WebScreenshots()
+}
+
+// uploadListPages upload the screenshot of every result page next to the image name, exp: xxx-p1.png
+//  @return bool false if a page is not uploaded
+func uploadListPages(imageName string, format tools.ImageFormat, result *capture.Capture, response *capture.ScreenshotsResult) bool {
+	for _, page := range result.Pages {
+		content, ext := encodeScreenshots(format, page.Screenshot)
+		key, _, ok := uploadScreenshots(oss.SiblingKey(imageName, fmt.Sprintf("-p%d", page.Page), ext), oss.ContentHash(content), content)
+		if !ok {
+			return false
+		}
+		if response.Screenshot == "" {
+			response.Screenshot = key
+		}
+		response.Pages = append(response.Pages, capture.ResultPage{Page: page.Page, Url: page.Url, Screenshot: key, Items: len(page.Items)})
+		response.Items = append(response.Items, page.Items...)
+	}
+	return true
+}
+
+// enqueueNewItems find the items not in the last capture of the list, record the items found,
+// and enqueue an item capture message for every new item if the request asks
+func enqueueNewItems(param capture.ScreenshotsParam, response *capture.ScreenshotsResult) {
+	var store = appConf.HistoryConf
+	list := param.ListName()
+	last, err := store.LastList(param.Channel, param.Country, list)
+	if err != nil {
+		log.Printf("Get last list %s.error: %v", list, err)
+		return
+	}
+
+	seen := map[string]bool{}
+	record := history.ListRecord{Channel: param.Channel, Country: param.Country, List: list, CapturedAt: time.Now().UTC()}
+	if last != nil {
+		for _, id := range last.ItemIds {
+			seen[id] = true
+		}
+		// an item delisted for a while is not new when it is back
+		record.ItemIds = last.ItemIds
+	}
+	for _, item := range response.Items {
+		if !seen[item.ItemId] {
+			seen[item.ItemId] = true
+			response.NewItems = append(response.NewItems, item.ItemId)
+			record.ItemIds = append(record.ItemIds, item.ItemId)
+		}
+	}
+	if err = store.SaveList(record); err != nil {
+		log.Println(err)
+	}
+
+	if !param.Enqueue {
+		return
+	}
+	conn := middleware.Connection{
+		Url:          appConf.AmpqConf.Url,
+		Exchange:     appConf.AmpqConf.Exchange,
+		ExchangeType: "direct",
+		Queue:        appConf.ConsumeQueue,
+	}
+	for _, id := range response.NewItems {
+		message, _ := json.Marshal(capture.ScreenshotsParam{
+			Channel:    param.Channel,
+			Country:    param.Country,
+			Asin:       id,
+			PostalCode: param.PostalCode,
+		})
+		if err = conn.Publish(string(message)); err != nil {
+			log.Printf("Enqueue item %s error: %v", id, err)
+			continue
+		}
+		response.Enqueued++
+	}
+}
+
+// watermarkScreenshots render the provenance banner onto the screenshot
+func watermarkScreenshots(param capture.ScreenshotsParam, result *capture.Capture, imageBytes []byte) []byte {
+	var watermark = appConf.Watermark
+	if !watermark.Enabled {
+		return imageBytes
+	}
+
+	opt := tools.WatermarkOptions{Position: watermark.Position, Scale: watermark.Scale,
+		Background: watermark.background, Foreground: watermark.foreground}
+
+	lines := []string{
+		fmt.Sprintf("Captured at %s | Worker: %s", result.CapturedAt.UTC().Format("2006-01-02 15:04:05 MST"), watermark.WorkerID),
+		fmt.Sprintf("URL: %s", result.Url),
+		fmt.Sprintf("Channel: %s | Country: %s | Asin: %s | Price: %.2f", param.Channel, param.Country, param.Asin, result.Price),
+	}
+	watermarked, err := tools.WatermarkBytes(imageBytes, lines, opt, "png")
+	if err != nil {
+		log.Printf("Watermark screenshot.error: %v", err)
+		return imageBytes
+	}
+	return watermarked
+}
+
+// encodeScreenshots encode the png screenshot in the output format
+//  @return []byte the encoded screenshot, or the png screenshot if it fails to encode
+//  @return string the extension of object key
+func encodeScreenshots(format tools.ImageFormat, imageBytes []byte) ([]byte, string) {
+	// the screenshot is a png of default compression already
+	if compression := strings.ToLower(format.Compression); format.Ext() == "png" && (compression == "" || compression == "default") {
+		return imageBytes, "png"
+	}
+	encoded, err := tools.EncodeBytes(imageBytes, format)
+	if err != nil {
+		log.Printf("Encode screenshot as %s.error: %v, keep png", format.Ext(), err)
+		return imageBytes, "png"
+	}
+	return encoded, format.Ext()
+}
+
+// uploadThumbnails upload the thumbnails of the png screenshot next to the image name, exp: xxx-w320.webp
+func uploadThumbnails(imageName string, format tools.ImageFormat, response *capture.ScreenshotsResult, imageBytes []byte) {
+	var thumbnailConf = appConf.ThumbnailConf
+	if len(thumbnailConf.Widths) == 0 {
+		return
+	}
+
+	thumbnails, err := tools.ThumbnailBytes(imageBytes, thumbnailConf.Widths, thumbnailConf.filter, format)
+	if err != nil {
+		log.Printf("Thumbnail screenshot.error: %v", err)
+		return
+	}
+	for _, thumbnail := range thumbnails {
+		width := strconv.Itoa(thumbnail.Width)
+		key, _, ok := uploadScreenshots(oss.SiblingKey(imageName, "-w"+width, format.Ext()), oss.ContentHash(thumbnail.Bytes), thumbnail.Bytes)
+		if !ok {
+			continue
+		}
+		if response.Thumbnails == nil {
+			response.Thumbnails = map[string]string{}
+		}
+		response.Thumbnails[width] = key
+	}
+}
+
+// uploadScreenshots Upload images to oss, an identical image uploaded before is reused when dedup is enabled
+//  @param hash is the content hash of image, the watermark is not hashed
+//  @return string the object key of image
+//  @return bool whether the key of a previous upload is reused
+//  @return bool whether success
+func uploadScreenshots(imageName string, hash string, imageBytes []byte) (string, bool, bool) {
+	var aliOss = appConf.OssConf
+	key, deduplicated, ok := aliOss.PutBytesDedup(imageName, hash, imageBytes)
+	if ok {
+		log.Printf("Upload %s to oss, success ! deduplicated: %v", key, deduplicated)
+	}
+	return key, deduplicated, ok
+}
+
+// getScreenshotsName is used to generate object key of tarantula by the key template of oss
+//  @param ext is the extension of the output format, exp: png, webp
+func getScreenshotsName(param capture.ScreenshotsParam, ext string) string {
+	fields := map[string]string{
+		"channel": param.Channel,
+		"country": param.Country,
+		"asin":    param.Asin,
+		"ext":     ext,
+	}
+	if param.IsList() {
+		fields["asin"] = param.ListName()
+	}
+	return oss.KeyTemplate(appConf.OssConf.KeyTemplate).Render(fields, time.Now())
+}
+
+// compareWithLastCapture set the visual change between the screenshot and the last capture of the item,
+// then record the screenshot as the last capture
+//  @param imageName the object key generated for the screenshot, the diff picture is uploaded next to it
+//  @param imageBytes the png screenshot before the watermark
+//  @param stored indicates whether the screenshot object is imageBytes,
+//  otherwise imageBytes is uploaded as the reference compared by the diff of next capture
+func compareWithLastCapture(param capture.ScreenshotsParam, imageName string, response *capture.ScreenshotsResult, imageBytes []byte, stored bool) {
+	var store = appConf.HistoryConf
+	if !store.Enabled {
+		return
+	}
+
+	hash, err := tools.DHashBytes(imageBytes)
+	if err != nil {
+		log.Printf("Perceptual hash of screenshot.error: %v", err)
+		return
+	}
+	response.PHash = tools.FormatHash(hash)
+
+	last, err := store.Last(param.Channel, param.Country, param.Asin)
+	if err != nil {
+		log.Printf("Load last capture.error: %v", err)
+	}
+	if last != nil {
+		lastHash, err := tools.ParseHash(last.Hash)
+		if err == nil {
+			response.PreviousScreenshot = last.Screenshot
+			response.VisualChange = tools.HashDifference(lastHash, hash)
+			response.VisualChanged = response.VisualChange > store.Threshold
+		}
+		if store.UploadDiff && last.Screenshot != response.Screenshot {
+			lastKey := last.Screenshot
+			if last.Reference != "" {
+				lastKey = last.Reference
+			}
+			uploadDiffWithLastCapture(lastKey, imageName, response, imageBytes)
+		}
+	}
+
+	reference := ""
+	if store.UploadDiff && !stored {
+		reference = oss.SiblingKey(imageName, "-raw", "png")
+		if !appConf.OssConf.PutBytesOnOSS(reference, imageBytes) {
+			reference = ""
+		}
+	}
+
+	err = store.Save(history.Record{
+		Channel:    param.Channel,
+		Country:    param.Country,
+		Asin:       param.Asin,
+		Screenshot: response.Screenshot,
+		Hash:       response.PHash,
+		Price:      response.NewPrice,
+		CapturedAt: time.Now().UTC(),
+		Reference:  reference,
+	})
+	if err != nil {
+		log.Printf("Save last capture.error: %v", err)
+	}
+}
+
+// uploadDiffWithLastCapture upload a picture highlighting the changed regions next to the screenshot
+func uploadDiffWithLastCapture(lastKey string, imageName string, response *capture.ScreenshotsResult, imageBytes []byte) {
+	var aliOss = appConf.OssConf
+	lastBytes, err := aliOss.GetBytesFromOSS(lastKey)
+	if err != nil {
+		log.Printf("Download last capture %s.error: %v", lastKey, err)
+		return
+	}
+
+	diffBytes, diff, err := tools.DiffPicsBytes(lastBytes, imageBytes, tools.DiffOptions{}, "png")
+	if err != nil {
+		log.Printf("Diff with last capture.error: %v", err)
+		return
+	}
+	response.DiffRegions = len(diff.Regions)
+	if len(diff.Regions) == 0 {
+		return
+	}
+
+	diffKey := oss.SiblingKey(imageName, "-diff", "png")
+	if aliOss.PutBytesOnOSS(diffKey, diffBytes) {
+		response.DiffScreenshot = diffKey
+	}
+}
+
+// uploadPageSource upload the gzip compressed page source next to the image name, exp: xxx-page.html.gz,
+// with the snapshot record xxx-page.json
+func uploadPageSource(param capture.ScreenshotsParam, imageName string, result *capture.Capture, response *capture.ScreenshotsResult) {
+	var pageSource = appConf.PageSource
+	if !pageSource.Enabled {
+		return
+	}
+
+	upload := func(source string, suffix string) string {
+		if source == "" {
+			return ""
+		}
+		content, err := tools.GzipBytes([]byte(source))
+		if err != nil {
+			log.Printf("Gzip page source.error: %v", err)
+			return ""
+		}
+		key := oss.SiblingKey(imageName, suffix, "html.gz")
+		if !appConf.OssConf.PutBytesOnOSS(key, content) {
+			return ""
+		}
+		return key
+	}
+	response.Html = upload(result.PageSource, "-page")
+	response.FrameHtml = upload(result.FrameSource, "-frame")
+	if response.Html == "" {
+		return
+	}
+
+	// what was extracted from the page source, compared by the reextract command
+	snapshot, err := json.Marshal(capture.Snapshot{
+		Url:           result.Url,
+		Channel:       param.Channel,
+		Country:       param.Country,
+		Asin:          param.Asin,
+		PostalCode:    result.PostalCode,
+		Price:         result.Price,
+		Status:        result.Status,
+		CapturedAt:    result.CapturedAt.UTC(),
+		Screenshot:    response.Screenshot,
+		Html:          response.Html,
+		FrameHtml:     response.FrameHtml,
+		Attributes:    result.Attributes,
+		ItemSpecifics: result.ItemSpecifics,
+	})
+	if err != nil {
+		log.Printf("Snapshot json.serialize_error: %v", err)
+		return
+	}
+	appConf.OssConf.PutBytesOnOSS(oss.SiblingKey(imageName, "-page", "json"), snapshot)
+}
+
+// signEvidence upload the signed manifest of the capture next to the image name
+func signEvidence(param capture.ScreenshotsParam, imageName string, result *capture.Capture, response *capture.ScreenshotsResult, imageBytes []byte) {
+	var evidenceConf = appConf.EvidenceConf
+	if !evidenceConf.Enabled {
+		return
+	}
+	// a reused screenshot carries the watermark of its first capture, the stored object is signed
+	if response.Deduplicated && appConf.Watermark.Enabled {
+		stored, err := appConf.OssConf.GetBytesFromOSS(response.Screenshot)
+		if err != nil {
+			log.Printf("Download screenshot %s.error: %v", response.Screenshot, err)
+			return
+		}
+		imageBytes = stored
+	}
+
+	host, _ := os.Hostname()
+	manifest := evidence.Manifest{
+		Url:        result.Url,
+		Channel:    param.Channel,
+		Country:    param.Country,
+		Asin:       param.Asin,
+		PostalCode: result.PostalCode,
+		Price:      result.Price,
+		Status:     result.Status,
+		StartedAt:  result.StartedAt.UTC(),
+		CapturedAt: result.CapturedAt.UTC(),
+		Browser:    result.Browser,
+		UserAgent:  result.UserAgent,
+		WorkerHost: host,
+		Screenshot: evidence.NewArtifact(response.Screenshot, imageBytes),
+		Html:       evidence.NewArtifact(response.Html, []byte(result.PageSource)),
+	}
+	content, signature, err := evidence.Sign(manifest, evidenceConf.privateKey)
+	if err != nil {
+		log.Printf("Sign evidence manifest.error: %v", err)
+		return
+	}
+
 	var aliOss = appConf.OssConf
-	aliOss.PutBytesOnOSS(imageName, imageBytes)
-	fmt.Printf("Upload %s to oss, success !\n", imageName)
+	manifestKey := oss.SiblingKey(imageName, "-manifest", "json")
+	if aliOss.PutBytesOnOSS(manifestKey, content) && aliOss.PutBytesOnOSS(manifestKey+".sig", signature) {
+		response.Manifest = manifestKey
+	}
 }
 
-// getScreenshotsName is used to generate filename of tarantula
-func getScreenshotsName(param capture.ScreenshotsParam) string {
-	timeStr := time.Now().Format("20060102150405")
-	return fmt.Sprintf("%s_%s_%s_%s.png", param.Channel, param.Country, param.Asin, timeStr)
+// uploadDebugArtifacts upload the state of web page under the debug prefix when the capture fails
+func uploadDebugArtifacts(imageName string, result *capture.Capture, response *capture.ScreenshotsResult) {
+	var debugConf = appConf.DebugConf
+	if result.Debug == nil {
+		return
+	}
+
+	prefix := debugConf.Prefix
+	if prefix == "" {
+		prefix = "debug/"
+	}
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	var aliOss = appConf.OssConf
+	artifacts := map[string]string{}
+	upload := func(name string, key string, content []byte) {
+		if len(content) > 0 && aliOss.PutBytesOnOSS(prefix+key, content) {
+			artifacts[name] = prefix + key
+		}
+	}
+
+	debug := result.Debug
+	upload("screenshot", oss.SiblingKey(imageName, "-viewport", "png"), debug.Screenshot)
+	if html, err := tools.GzipBytes([]byte(debug.PageSource)); err == nil && debug.PageSource != "" {
+		upload("html", oss.SiblingKey(imageName, "-page", "html.gz"), html)
+	}
+	info, err := json.MarshalIndent(map[string]interface{}{
+		"requestUrl":     result.Url,
+		"url":            debug.Url,
+		"title":          debug.Title,
+		"status":         result.Status,
+		"failedSelector": result.FailedSelector,
+		"consoleLogs":    debug.ConsoleLogs,
+	}, "", "  ")
+	if err == nil {
+		upload("info", oss.SiblingKey(imageName, "-info", "json"), info)
+	}
+	if len(artifacts) > 0 {
+		response.DebugArtifacts = artifacts
+	}
 }
 
-//
-func publishScreenshotsResult(msg string, price float32, cutName string, status string) {
+// newScreenshotsResult make the result from the request message, the request fields are kept
+func newScreenshotsResult(msg string) capture.ScreenshotsResult {
 	response := capture.ScreenshotsResult{}
 	err := json.Unmarshal([]byte(msg), &response)
 	if err != nil {
 		log.Fatalf("middleware message.format_error: %v", err)
 	}
-	response.Status = status
-	response.NewPrice = price
-	response.Screenshot = cutName
+	return response
+}
 
+// publishScreenshotsResult Publish the result into the publish queue
+func publishScreenshotsResult(response capture.ScreenshotsResult) {
 	rsJson, err := json.Marshal(response)
 	if err != nil {
 		log.Fatalf("Resonse json.serialize_error: %v", err)
@@ -124,6 +992,33 @@ func publishScreenshotsResult(msg string, price float32, cutName string, status
 	}
 }
 
+// delayRequest republish the request message to the consume queue after the delay
+func delayRequest(msg string, delay time.Duration) error {
+	conn := middleware.Connection{
+		Url:          appConf.AmpqConf.Url,
+		Exchange:     appConf.AmpqConf.Exchange,
+		ExchangeType: "direct",
+		Queue:        appConf.ConsumeQueue,
+	}
+	return conn.PublishDelayed(msg, delay)
+}
+
+// delayCoolingDown delay the request of the site cooling down by republishing it
+//  @param delay republish the message after the delay, exp: delayRequest
+//  @return bool whether the request is delayed, false if the site is not cooling down or it fails to delay
+func delayCoolingDown(site string, msg string, delay func(string, time.Duration) error) bool {
+	wait := blockTracker.CoolingDown(site)
+	if wait <= 0 {
+		return false
+	}
+	log.Printf("Site %s is cooling down, delay the request %v", site, wait)
+	if err := delay(msg, wait); err != nil {
+		log.Printf("Delay request.error: %v, capture now", err)
+		return false
+	}
+	return true
+}
+
 // consumeCallback is the RabbitMQ consumer callback function
 // @param msg string
 func consumeCallback(msg string) {
@@ -133,24 +1028,122 @@ func consumeCallback(msg string) {
 	if err != nil {
 		log.Fatalf("middleware message.format_error: %v", err)
 	} //json解析到结构体里面
+	response := newScreenshotsResult(msg)
+	// a page of generic channel is named by its url, for the object key and the last capture
+	if strings.EqualFold(param.Channel, capture.GENERIC_CHANNEL) && param.Asin == "" {
+		param.Asin = urlName(param.Url)
+	}
+
+	// the proxy is local to the country of request, a site is backed off per proxy
+	site := strings.ToLower(param.Channel)
+	proxy := appConf.ProxyPool.Pick(param.Country)
+	if proxy != nil {
+		site = fmt.Sprintf("%s@%s", site, proxy)
+		response.Proxy = proxy.String()
+	}
+
+	// back off the site blocked recently, the request is delayed in the queue instead of blocking the consumer
+	if delayCoolingDown(site, msg, delayRequest) {
+		return
+	}
 
 	// get []byte of tarantula
-	price, imageBytes, status := getEbayWebScreenshots(param.Asin)
-	imageName := ""
-	if len(imageBytes) > 0 {
+	var result *capture.Capture
+	definition := appConf.Sites[strings.ToLower(param.Channel)]
+	stepsErr := capture.CheckScriptSteps(param.Steps, appConf.StepsConf.AllowScript)
+	switch {
+	case stepsErr != nil:
+		log.Println(stepsErr)
+		result = &capture.Capture{Status: string(capture.STEP_ERROR), Reason: stepsErr.Error()}
+	case definition != nil:
+		result = getDefinedWebScreenshots(definition, param, proxy)
+	case strings.EqualFold(param.Channel, capture.GENERIC_CHANNEL):
+		result = getGenericWebScreenshots(param, proxy)
+	case param.IsList():
+		result = getEbayListScreenshots(param, proxy)
+	default:
+		result = getEbayWebScreenshots(param, proxy)
+	}
+	status := result.Status
+	if status == string(capture.BLOCKED) {
+		blockTracker.Blocked(site, blockRule(param.Channel).Cooldown)
+	} else if status != string(capture.PAGE_ERROR) {
+		blockTracker.Passed(site)
+	}
+	// a blocked or unreachable proxy is quarantined, the next request rotates to another one
+	if proxy != nil {
+		if status == string(capture.BLOCKED) || status == string(capture.PAGE_ERROR) {
+			appConf.ProxyPool.Failed(proxy, strings.TrimSpace(status+" "+result.Reason))
+		} else {
+			appConf.ProxyPool.Succeeded(proxy)
+		}
+	}
+	response.Reason = result.Reason
+	response.NewPrice = result.Price
+	response.FailedSelector = result.FailedSelector
+	response.PostalCode = result.PostalCode
+	response.Shipping = result.Shipping
+	response.Attributes = result.Attributes
+	response.Fields = result.Fields
+	response.Steps = result.Steps
+	response.ItemSpecifics = result.ItemSpecifics
+	response.Variation = result.Variation
+	response.Variations = result.Variations
+	if len(param.ExpectedSpecifics) > 0 && status == string(capture.SUCCESS) {
+		response.SpecificsMismatches = capture.ValidateSpecifics(result.ItemSpecifics, param.ExpectedSpecifics)
+		matched := len(response.SpecificsMismatches) == 0
+		response.SpecificsMatched = &matched
+	}
+
+	// the artifacts of the capture are uploaded next to the image name,
+	// because the key of screenshot may be a previous one reused by dedup
+	format := outputFormat(param.Channel)
+	imageName := getScreenshotsName(param, format.Ext())
+	uploadDebugArtifacts(imageName, result, &response)
+	if len(result.Pages) > 0 {
+		if uploadListPages(imageName, format, result, &response) {
+			enqueueNewItems(param, &response)
+		} else {
+			status = string(capture.UPLOAD_TO_OSS_ERROR)
+		}
+	}
+	if len(result.Screenshot) > 0 {
+		// the screenshot is hashed, deduplicated and compared before the watermark,
+		// which differs by every capture, only the stored copy is watermarked
+		imageBytes := result.Screenshot
+		response.Sha256 = oss.ContentHash(imageBytes)
+		content, ext := encodeScreenshots(format, watermarkScreenshots(param, result, imageBytes))
+		if ext != format.Ext() {
+			imageName = oss.SiblingKey(imageName, "", ext)
+		}
+
 		// upload tarantula
-		imageName = getScreenshotsName(param)
-		var aliOss = appConf.OssConf
-		if !aliOss.PutBytesOnOSS(imageName, imageBytes) {
+		key, deduplicated, ok := uploadScreenshots(imageName, response.Sha256, content)
+		if ok {
+			response.Screenshot = key
+			response.Deduplicated = deduplicated
+			compareWithLastCapture(param, imageName, &response, imageBytes, bytes.Equal(content, imageBytes))
+			uploadThumbnails(imageName, format, &response, imageBytes)
+			uploadPageSource(param, imageName, result, &response)
+			signEvidence(param, imageName, result, &response, content)
+		} else {
 			status = string(capture.UPLOAD_TO_OSS_ERROR)
 		}
 	}
+	response.Status = status
 
 	// Publish tarantula result to RabbitMQ tarantula.callback
-	publishScreenshotsResult(msg, price, imageName, status)
+	publishScreenshotsResult(response)
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "verify" {
+		os.Exit(verifyCommand(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "reextract" {
+		os.Exit(reextractCommand(os.Args[2:]))
+	}
+
 	flag.Parse()
 	// set config of app
 	setAppConf()
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"gopkg.in/ini.v1"
+	"image/color"
+	"reflect"
+	"testing"
+	"time"
+	"y-clouds.com/tarantula/capture"
+	"y-clouds.com/tarantula/tools"
+)
+
+func TestWatermarkColors(t *testing.T) {
+	// the sample config, # starts a color instead of an inline comment
+	cfg, err := ini.LoadSources(confLoadOptions, "conf.ini")
+	if err != nil {
+		t.Fatal(err)
+	}
+	watermark := new(Watermark)
+	if err = cfg.Section("Watermark").MapTo(watermark); err != nil {
+		t.Fatal(err)
+	}
+	if err = watermark.parseColors(); err != nil {
+		t.Fatalf("parseColors() error: %v", err)
+	}
+	if watermark.background != (color.NRGBA{A: 0xff}) || watermark.foreground != (color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}) {
+		t.Errorf("colors = %v, %v, want #000000, #ffffff", watermark.background, watermark.foreground)
+	}
+
+	watermark = &Watermark{}
+	if err = watermark.parseColors(); err != nil || watermark.background != nil || watermark.foreground != nil {
+		t.Errorf("parseColors() of the default colors = %v, %v, %v, want nil", watermark.background, watermark.foreground, err)
+	}
+	for _, invalid := range []Watermark{{Background: "black"}, {Foreground: "#fff"}, {Background: "#00000g"}} {
+		if err = invalid.parseColors(); err == nil {
+			t.Errorf("parseColors() of %+v, want error", invalid)
+		}
+	}
+}
+
+func TestSampleLayout(t *testing.T) {
+	cfg, err := ini.LoadSources(confLoadOptions, "conf.ini")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var layout capture.Layout
+	if err = cfg.Section("Layout").MapTo(&layout); err != nil {
+		t.Fatal(err)
+	}
+	if err = layout.Validate(); err != nil {
+		t.Errorf("Validate() of the sample layout error: %v", err)
+	}
+	background, err := tools.ParseHexColor(layout.Background)
+	if err != nil || background != (color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}) {
+		t.Errorf("the sample layout background = %q (%v), want #ffffff", layout.Background, err)
+	}
+	if layout.Direction != "column" || layout.Align != "start" {
+		t.Errorf("the sample layout = %+v, want column / start", layout)
+	}
+
+}
+
+func TestSampleBlockRules(t *testing.T) {
+	cfg, err := ini.LoadSources(confLoadOptions, "conf.ini")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the sample keeps the built-in rule of ebay, a fragment like "To continue, please verify" can not be configured
+	rule := capture.DefaultBlockRules["ebay"]
+	if err = cfg.Section("Block.ebay").MapTo(&rule); err != nil {
+		t.Fatal(err)
+	}
+	if want := capture.DefaultBlockRules["ebay"]; !reflect.DeepEqual(rule.PageRule, want.PageRule) {
+		t.Errorf("the sample [Block.ebay] = %+v, want the built-in rule %+v", rule.PageRule, want.PageRule)
+	}
+}
+
+func TestDelayCoolingDown(t *testing.T) {
+	var delayed []time.Duration
+	delay := func(msg string, wait time.Duration) error {
+		if msg != "{}" {
+			t.Errorf("delayed message = %s, want {}", msg)
+		}
+		delayed = append(delayed, wait)
+		return nil
+	}
+	if delayCoolingDown("test-site", "{}", delay) || len(delayed) != 0 {
+		t.Errorf("delayCoolingDown() of a site never blocked, want not delayed")
+	}
+
+	blockTracker.Blocked("test-site", time.Minute)
+	defer blockTracker.Passed("test-site")
+	if !delayCoolingDown("test-site", "{}", delay) {
+		t.Errorf("delayCoolingDown() of a blocked site, want delayed")
+	}
+	if len(delayed) != 1 || delayed[0] > time.Minute || delayed[0] < time.Minute-time.Second {
+		t.Errorf("delayed = %v, want the cooldown", delayed)
+	}
+
+	// the request is captured now if it fails to delay
+	failed := func(string, time.Duration) error { return errors.New("connection refused") }
+	if delayCoolingDown("test-site", "{}", failed) {
+		t.Errorf("delayCoolingDown() failing to publish, want not delayed")
+	}
+}
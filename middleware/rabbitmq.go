@@ -4,6 +4,7 @@ import (
 	"context"
 	amqp "github.com/rabbitmq/amqp091-go"
 	"log"
+	"strconv"
 	"time"
 )
 
@@ -216,3 +217,70 @@ func (c *Connection) Publish(message string) error {
 
 	return nil
 }
+
+// PublishDelayed This a method is called to publish message into queue after the delay.
+// The message waits in the queue {Queue}.delay until it expires, then it is dead-lettered back to the queue,
+// so that no consumer is blocked while waiting. A message expires at the head of the delay queue only,
+// so it may wait longer than its delay behind a message of longer delay, never shorter.
+func (c *Connection) PublishDelayed(message string, delay time.Duration) error {
+	conn, err := amqp.Dial(c.Url)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	name, args := c.delayQueue()
+	delayQueue, err := ch.QueueDeclare(
+		name,  // name
+		true,  // durable
+		false, // delete when unused
+		false, // exclusive
+		false, // no-wait
+		args,  // arguments
+	)
+	if err != nil {
+		return err
+	}
+
+	if c.PublishTimeout == 0 {
+		c.PublishTimeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.PublishTimeout)
+	defer cancel()
+
+	return ch.PublishWithContext(ctx,
+		"",              // the default exchange routes to the queue of the name
+		delayQueue.Name, // routing key
+		false,           // mandatory
+		false,           // immediate
+		delayedPublishing(message, delay))
+}
+
+// delayQueue is the name and arguments of the queue where the delayed messages wait,
+// an expired message is dead-lettered to the exchange and routing key the queue is bound to, see Consumer
+func (c *Connection) delayQueue() (string, amqp.Table) {
+	return c.Queue + ".delay", amqp.Table{
+		"x-dead-letter-exchange":    c.Exchange,
+		"x-dead-letter-routing-key": c.Queue,
+	}
+}
+
+// delayedPublishing is the persistent message expiring after the delay, the expiration is at least 1ms
+func delayedPublishing(message string, delay time.Duration) amqp.Publishing {
+	expiration := delay.Milliseconds()
+	if expiration < 1 {
+		expiration = 1
+	}
+	return amqp.Publishing{
+		ContentType:  "text/plain",
+		Body:         []byte(message),
+		DeliveryMode: amqp.Persistent,
+		Expiration:   strconv.FormatInt(expiration, 10),
+	}
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	amqp "github.com/rabbitmq/amqp091-go"
+	"testing"
+	"time"
+)
+
+func TestDelayQueue(t *testing.T) {
+	c := Connection{Exchange: "tarantula", Queue: "screenshots"}
+	name, args := c.delayQueue()
+	if name != "screenshots.delay" {
+		t.Errorf("delayQueue() name = %s, want screenshots.delay", name)
+	}
+	// the expired message goes back to the consumed queue
+	if args["x-dead-letter-exchange"] != "tarantula" || args["x-dead-letter-routing-key"] != "screenshots" || len(args) != 2 {
+		t.Errorf("delayQueue() args = %v, want dead-lettered to tarantula/screenshots", args)
+	}
+	if err := args.Validate(); err != nil {
+		t.Errorf("delayQueue() args are not a valid table: %v", err)
+	}
+
+	// the default exchange routes by the queue name
+	_, args = (&Connection{Queue: "screenshots"}).delayQueue()
+	if args["x-dead-letter-exchange"] != "" || args["x-dead-letter-routing-key"] != "screenshots" {
+		t.Errorf("delayQueue() args of the default exchange = %v", args)
+	}
+}
+
+func TestDelayedPublishing(t *testing.T) {
+	tests := []struct {
+		delay time.Duration
+		want  string
+	}{
+		{10 * time.Minute, "600000"},
+		{1500 * time.Microsecond, "1"},
+		{0, "1"},
+		{-time.Second, "1"},
+	}
+	for _, tt := range tests {
+		msg := delayedPublishing("{}", tt.delay)
+		if msg.Expiration != tt.want {
+			t.Errorf("delayedPublishing() expiration of %v = %s, want %s", tt.delay, msg.Expiration, tt.want)
+		}
+		if msg.DeliveryMode != amqp.Persistent || string(msg.Body) != "{}" {
+			t.Errorf("delayedPublishing() = %+v, want the persistent message", msg)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package oss
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"strings"
+)
+
+const defaultDedupPrefix = "dedup/sha256/"
+
+// ContentHash is the hex SHA-256 of the content, used to address identical screenshots
+func ContentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// dedupIndexKey is the key of index object, the content of the index object is the key of screenshot object
+func (aliOss AliOss) dedupIndexKey(hash string) string {
+	prefix := aliOss.DedupPrefix
+	if prefix == "" {
+		prefix = defaultDedupPrefix
+	}
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return prefix + hash
+}
+
+// PutBytesDedup
+//  @Description: Upload the content unless an identical content was uploaded before.
+//  An index object named by the content hash records the key of the first upload,
+//  so identical content reuse that key instead of uploading a new object.
+//  @receiver aliOss
+//  @param objectKey is the key used when the content is new
+//  @param hash is the hash addressing identical content, exp: the ContentHash of the screenshot before the watermark
+//  @param content []byte
+//  @return string the key of the object holding the content
+//  @return bool whether the key of a previous upload is reused
+//  @return bool whether success
+func (aliOss AliOss) PutBytesDedup(objectKey string, hash string, content []byte) (string, bool, bool) {
+	if !aliOss.Dedup {
+		return objectKey, false, aliOss.PutBytesOnOSS(objectKey, content)
+	}
+
+	indexKey := aliOss.dedupIndexKey(hash)
+	exist, err := aliOss.IsObjectExist(indexKey)
+	if err != nil {
+		log.Printf("oss.dedup index check failed: %v", err)
+	}
+	if exist {
+		existKey, err := aliOss.GetBytesFromOSS(indexKey)
+		if err != nil {
+			log.Printf("oss.dedup index read failed: %v", err)
+		} else if ok, _ := aliOss.IsObjectExist(string(existKey)); ok {
+			// the object may be removed by lifecycle rules, then upload again
+			log.Printf("oss.dedup reuse %s for %s", existKey, objectKey)
+			return string(existKey), true, true
+		}
+	}
+
+	if !aliOss.PutBytesOnOSS(objectKey, content) {
+		return objectKey, false, false
+	}
+	if !aliOss.PutBytesOnOSS(indexKey, []byte(objectKey)) {
+		log.Printf("oss.dedup index %s write failed", indexKey)
+	}
+
+	return objectKey, false, true
+}
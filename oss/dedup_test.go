@@ -0,0 +1,113 @@
+package oss
+
+import (
+	"testing"
+	"y-clouds.com/tarantula/oss/osstest"
+)
+
+func newFakeOss(t *testing.T) (AliOss, *osstest.Bucket) {
+	bucket := osstest.NewBucket(t)
+	return AliOss{Endpoint: bucket.Endpoint, AccessID: "id", AccessKey: "key", BucketName: bucket.Name, Dedup: true}, bucket
+}
+
+// object is the content of object in the bucket as a string
+func object(bucket *osstest.Bucket, key string) string {
+	content, _ := bucket.Object(key)
+	return string(content)
+}
+
+func TestContentHash(t *testing.T) {
+	// sha256 of "abc"
+	if got, want := ContentHash([]byte("abc")), "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"; got != want {
+		t.Errorf("ContentHash() = %s, want %s", got, want)
+	}
+	if ContentHash([]byte("a")) == ContentHash([]byte("b")) {
+		t.Errorf("ContentHash() is the same for different contents")
+	}
+}
+
+func TestDedupIndexKey(t *testing.T) {
+	tests := []struct {
+		prefix string
+		want   string
+	}{
+		{"", "dedup/sha256/abc"},
+		{"index", "index/abc"},
+		{"index/", "index/abc"},
+	}
+	for _, tt := range tests {
+		if got := (AliOss{DedupPrefix: tt.prefix}).dedupIndexKey("abc"); got != tt.want {
+			t.Errorf("dedupIndexKey() with prefix %q = %q, want %q", tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestPutBytesDedup(t *testing.T) {
+	aliOss, bucket := newFakeOss(t)
+
+	key, reused, ok := aliOss.PutBytesDedup("a.png", ContentHash([]byte("picture")), []byte("picture"))
+	if key != "a.png" || reused || !ok {
+		t.Fatalf("first upload = (%s, %v, %v), want (a.png, false, true)", key, reused, ok)
+	}
+	if got := object(bucket, "dedup/sha256/"+ContentHash([]byte("picture"))); got != "a.png" {
+		t.Fatalf("index object = %q, want a.png", got)
+	}
+
+	key, reused, ok = aliOss.PutBytesDedup("b.png", ContentHash([]byte("picture")), []byte("picture"))
+	if key != "a.png" || !reused || !ok {
+		t.Errorf("identical upload = (%s, %v, %v), want (a.png, true, true)", key, reused, ok)
+	}
+	if _, exist := bucket.Object("b.png"); exist {
+		t.Errorf("identical content is uploaded again")
+	}
+
+	key, reused, ok = aliOss.PutBytesDedup("c.png", ContentHash([]byte("another picture")), []byte("another picture"))
+	if key != "c.png" || reused || !ok {
+		t.Errorf("different upload = (%s, %v, %v), want (c.png, false, true)", key, reused, ok)
+	}
+}
+
+func TestPutBytesDedupRemovedObject(t *testing.T) {
+	aliOss, bucket := newFakeOss(t)
+
+	aliOss.PutBytesDedup("a.png", ContentHash([]byte("picture")), []byte("picture"))
+	// the object is removed by lifecycle rules, the index is stale
+	bucket.Delete("a.png")
+
+	key, reused, ok := aliOss.PutBytesDedup("b.png", ContentHash([]byte("picture")), []byte("picture"))
+	if key != "b.png" || reused || !ok {
+		t.Errorf("upload after removal = (%s, %v, %v), want (b.png, false, true)", key, reused, ok)
+	}
+	if got := object(bucket, "dedup/sha256/"+ContentHash([]byte("picture"))); got != "b.png" {
+		t.Errorf("index object = %q, want b.png", got)
+	}
+}
+
+func TestPutBytesDedupDisabled(t *testing.T) {
+	aliOss, bucket := newFakeOss(t)
+	aliOss.Dedup = false
+
+	aliOss.PutBytesDedup("a.png", ContentHash([]byte("picture")), []byte("picture"))
+	key, reused, ok := aliOss.PutBytesDedup("b.png", ContentHash([]byte("picture")), []byte("picture"))
+	if key != "b.png" || reused || !ok {
+		t.Errorf("upload = (%s, %v, %v), want (b.png, false, true)", key, reused, ok)
+	}
+	if bucket.Puts() != 2 {
+		t.Errorf("uploads = %d, want 2 without index objects", bucket.Puts())
+	}
+}
+
+func TestPutBytesDedupByHash(t *testing.T) {
+	aliOss, bucket := newFakeOss(t)
+	hash := ContentHash([]byte("picture"))
+
+	// the stored copies differ by their watermark, but they are the same screenshot
+	aliOss.PutBytesDedup("a.png", hash, []byte("picture captured at 1"))
+	key, reused, ok := aliOss.PutBytesDedup("b.png", hash, []byte("picture captured at 2"))
+	if key != "a.png" || !reused || !ok {
+		t.Errorf("upload = (%s, %v, %v), want (a.png, true, true)", key, reused, ok)
+	}
+	if got := object(bucket, "a.png"); got != "picture captured at 1" {
+		t.Errorf("object = %q, want the first copy", got)
+	}
+}
@@ -0,0 +1,102 @@
+package oss
+
+import (
+	"mime"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"y-clouds.com/tarantula/tools"
+)
+
+// DefaultKeyTemplate keeps the flat naming of the bucket root, but with UTC time and a unique suffix
+const DefaultKeyTemplate = "{channel}_{country}_{asin}_{date:20060102150405}_{uuid}.{ext}"
+
+var keyPlaceholder = regexp.MustCompile(`\{([a-zA-Z]+)(?::([^}]*))?}`)
+
+// KeyTemplate is the template of object key, placeholders are replaced when rendering:
+//  {date:layout} the capture time in UTC, formatted by go time layout, exp: {date:2006/01/02}
+//  {timestamp}   the capture time in UTC, unix milliseconds
+//  {uuid}        a random unique suffix
+//  {name}        any field passed to Render, exp: {channel} {country} {asin} {ext}
+// exp: {channel}/{country}/{date:2006/01/02}/{asin}/{uuid}.{ext}
+type KeyTemplate string
+
+// Render
+//  @Description: Make object key by the template
+//  @receiver t
+//  @param fields is the value of placeholders, a "/" in the value is replaced by "-"
+//  @param now is the capture time
+//  @return string
+func (t KeyTemplate) Render(fields map[string]string, now time.Time) string {
+	tpl := string(t)
+	if strings.TrimSpace(tpl) == "" {
+		tpl = DefaultKeyTemplate
+	}
+	now = now.UTC()
+
+	key := keyPlaceholder.ReplaceAllStringFunc(tpl, func(placeholder string) string {
+		m := keyPlaceholder.FindStringSubmatch(placeholder)
+		name, layout := m[1], m[2]
+		switch name {
+		case "date":
+			if layout == "" {
+				layout = "20060102150405"
+			}
+			return now.Format(layout)
+		case "timestamp":
+			return strconv.FormatInt(now.UnixMilli(), 10)
+		case "uuid":
+			return tools.NewUUID()
+		}
+		if value, ok := fields[name]; ok {
+			return strings.ReplaceAll(value, "/", "-")
+		}
+		return placeholder
+	})
+
+	// the object key of oss can not start with "/"
+	return strings.TrimLeft(key, "/")
+}
+
+// SiblingKey make an object key next to the key, exp: a/b/c.png => a/b/c-diff.png
+//  @param key is the origin object key
+//  @param suffix is appended to the name of key
+//  @param ext is the new extension without ".", keep the origin extension if empty
+func SiblingKey(key string, suffix string, ext string) string {
+	base, origExt := key, ""
+	if i := strings.LastIndex(key, "."); i > strings.LastIndex(key, "/") {
+		base, origExt = key[:i], key[i+1:]
+	}
+	if ext == "" {
+		ext = origExt
+	}
+	if ext == "" {
+		return base + suffix
+	}
+	return base + suffix + "." + ext
+}
+
+// imageContentTypes is the content type of picture by extension, not depending on the mime types of the system
+var imageContentTypes = map[string]string{
+	"png":  "image/png",
+	"jpg":  "image/jpeg",
+	"jpeg": "image/jpeg",
+	"webp": "image/webp",
+}
+
+// ContentTypeOf is the content type of object by the extension of key, exp: image/webp
+//  @param key is the object key
+func ContentTypeOf(key string) string {
+	ext := ""
+	if i := strings.LastIndex(key, "."); i > strings.LastIndex(key, "/") {
+		ext = strings.ToLower(key[i+1:])
+	}
+	if contentType, ok := imageContentTypes[ext]; ok {
+		return contentType
+	}
+	if contentType := mime.TypeByExtension("." + ext); ext != "" && contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
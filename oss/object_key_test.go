@@ -0,0 +1,91 @@
+package oss
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKeyTemplateRender(t *testing.T) {
+	now := time.Date(2022, 7, 1, 8, 30, 5, 123e6, time.FixedZone("CST", 8*3600))
+	fields := map[string]string{"channel": "ebay", "country": "US", "asin": "1234/5", "ext": "png"}
+
+	tests := []struct {
+		name     string
+		template KeyTemplate
+		want     string
+	}{
+		{"fields", "{channel}/{country}/{asin}.{ext}", "ebay/US/1234-5.png"},
+		{"date in utc", "{date:2006/01/02/15}/{asin}.{ext}", "2022/07/01/00/1234-5.png"},
+		{"default date layout", "{date}", "20220701003005"},
+		{"timestamp", "{timestamp}", "1656635405123"},
+		{"unknown placeholder kept", "{channel}/{unknown}.{ext}", "ebay/{unknown}.png"},
+		{"leading slash trimmed", "/{channel}/{asin}", "ebay/1234-5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.template.Render(fields, now); got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyTemplateRenderDefault(t *testing.T) {
+	now := time.Date(2022, 7, 1, 8, 30, 5, 0, time.UTC)
+	fields := map[string]string{"channel": "ebay", "country": "US", "asin": "1234", "ext": "png"}
+
+	key := KeyTemplate(" ").Render(fields, now)
+	pattern := regexp.MustCompile(`^ebay_US_1234_20220701083005_[0-9a-zA-Z-]+\.png$`)
+	if !pattern.MatchString(key) {
+		t.Errorf("Render() = %q, want match %s", key, pattern)
+	}
+	if strings.Contains(key, "{") {
+		t.Errorf("Render() = %q, placeholder not replaced", key)
+	}
+}
+
+func TestKeyTemplateRenderUnique(t *testing.T) {
+	now := time.Now()
+	template := KeyTemplate("{channel}/{uuid}.png")
+	fields := map[string]string{"channel": "ebay"}
+	if a, b := template.Render(fields, now), template.Render(fields, now); a == b {
+		t.Errorf("Render() = %q twice, want unique keys", a)
+	}
+}
+
+func TestSiblingKey(t *testing.T) {
+	tests := []struct {
+		key, suffix, ext string
+		want             string
+	}{
+		{"a/b/c.png", "-diff", "", "a/b/c-diff.png"},
+		{"a/b/c.png", "", "html.gz", "a/b/c.html.gz"},
+		{"a/b/c.png", "-w320", "webp", "a/b/c-w320.webp"},
+		{"a/b.d/c", "-diff", "", "a/b.d/c-diff"},
+		{"a/b.d/c", "", "json", "a/b.d/c.json"},
+		{"c", "-diff", "png", "c-diff.png"},
+	}
+	for _, tt := range tests {
+		if got := SiblingKey(tt.key, tt.suffix, tt.ext); got != tt.want {
+			t.Errorf("SiblingKey(%q, %q, %q) = %q, want %q", tt.key, tt.suffix, tt.ext, got, tt.want)
+		}
+	}
+}
+
+func TestContentTypeOf(t *testing.T) {
+	tests := map[string]string{
+		"a/b.png":   "image/png",
+		"a/b.JPG":   "image/jpeg",
+		"a/b.webp":  "image/webp",
+		"a/b.json":  "application/json",
+		"a.b/c":     "application/octet-stream",
+		"a/b.zzzzz": "application/octet-stream",
+	}
+	for key, want := range tests {
+		if got := ContentTypeOf(key); got != want {
+			t.Errorf("ContentTypeOf(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
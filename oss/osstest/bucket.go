@@ -0,0 +1,96 @@
+// Package osstest is an in-memory oss bucket for the tests of the packages storing objects in oss
+package osstest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Bucket is an in-memory bucket served as path-style oss api, the endpoint is an ip so the sdk use path style
+type Bucket struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	puts    int
+	// Endpoint is the url of the bucket server, exp: http://127.0.0.1:34567
+	Endpoint string
+	// Name is the bucket name of the requests
+	Name string
+}
+
+// NewBucket start the bucket server, it is closed when the test finishes
+func NewBucket(t *testing.T) *Bucket {
+	bucket := &Bucket{objects: map[string][]byte{}, Name: "bucket"}
+	server := httptest.NewServer(bucket)
+	t.Cleanup(server.Close)
+	bucket.Endpoint = server.URL
+	return bucket
+}
+
+func (b *Bucket) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// /bucket/object
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	if len(parts) != 2 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	key := parts[1]
+	switch r.Method {
+	case http.MethodPut:
+		content, _ := io.ReadAll(r.Body)
+		b.objects[key] = content
+		b.puts++
+		w.WriteHeader(http.StatusOK)
+	case http.MethodHead, http.MethodGet:
+		content, ok := b.objects[key]
+		if !ok {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			if r.Method == http.MethodGet {
+				_, _ = io.WriteString(w, "<Error><Code>NoSuchKey</Code><Message>not found</Message></Error>")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(content)
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// Object get the content of object
+func (b *Bucket) Object(key string) ([]byte, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	content, ok := b.objects[key]
+	return content, ok
+}
+
+// Put store the object without a request
+func (b *Bucket) Put(key string, content []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.objects[key] = content
+}
+
+// Delete remove the object
+func (b *Bucket) Delete(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.objects, key)
+}
+
+// Puts is the number of uploads
+func (b *Bucket) Puts() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.puts
+}
@@ -3,6 +3,7 @@ package oss
 import (
 	"bytes"
 	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"io"
 	"log"
 )
 
@@ -12,25 +13,35 @@ type AliOss struct {
 	AccessID   string
 	AccessKey  string
 	BucketName string
+	// KeyTemplate is the template of screenshot object key, see KeyTemplate
+	KeyTemplate string
+	// Dedup indicates whether to reuse the object of an identical screenshot instead of uploading again
+	Dedup bool
+	// DedupPrefix is the prefix of the content hash index objects, default: dedup/sha256/
+	DedupPrefix string
 }
 
-// PutBytesOnOSS illustrates two methods for uploading a file: simple upload and multipart upload.
-//  @param objectKey like filename need suffix，exp: oss-image.png
-//  @param imgByte []byte
-func (aliOss AliOss) PutBytesOnOSS(objectKey string, imgByte []byte) bool {
+// bucket open the bucket of the configuration
+func (aliOss AliOss) bucket() (*oss.Bucket, error) {
 	client, err := oss.New(aliOss.Endpoint, aliOss.AccessID, aliOss.AccessKey)
 	if err != nil {
-		log.Printf("oss.bucket failed: %v", err)
-		return false
+		return nil, err
 	}
 
-	bucket, err := client.Bucket(aliOss.BucketName)
+	return client.Bucket(aliOss.BucketName)
+}
+
+// PutBytesOnOSS illustrates two methods for uploading a file: simple upload and multipart upload.
+//  @param objectKey like filename need suffix，exp: oss-image.png, the content type is set by the suffix
+//  @param imgByte []byte
+func (aliOss AliOss) PutBytesOnOSS(objectKey string, imgByte []byte) bool {
+	bucket, err := aliOss.bucket()
 	if err != nil {
 		log.Printf("oss.bucket failed: %v", err)
 		return false
 	}
 
-	err = bucket.PutObject(objectKey, bytes.NewReader(imgByte))
+	err = bucket.PutObject(objectKey, bytes.NewReader(imgByte), oss.ContentType(ContentTypeOf(objectKey)))
 	if err != nil {
 		log.Printf("oss.bytes upload failed: %v", err)
 		return false
@@ -39,6 +50,59 @@ func (aliOss AliOss) PutBytesOnOSS(objectKey string, imgByte []byte) bool {
 	return true
 }
 
+// IsObjectExist check whether the object exists in the bucket
+//  @param objectKey the object key
+func (aliOss AliOss) IsObjectExist(objectKey string) (bool, error) {
+	bucket, err := aliOss.bucket()
+	if err != nil {
+		return false, err
+	}
+
+	return bucket.IsObjectExist(objectKey)
+}
+
+// GetBytesFromOSS download the object as []byte
+//  @param objectKey the object key
+func (aliOss AliOss) GetBytesFromOSS(objectKey string) ([]byte, error) {
+	bucket, err := aliOss.bucket()
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := bucket.GetObject(objectKey)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	return io.ReadAll(body)
+}
+
+// ListObjectKeys list the keys of all objects with the prefix
+//  @param prefix the prefix of object key, exp: ebay/US/
+func (aliOss AliOss) ListObjectKeys(prefix string) ([]string, error) {
+	bucket, err := aliOss.bucket()
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	marker := ""
+	for {
+		lor, err := bucket.ListObjects(oss.Prefix(prefix), oss.Marker(marker))
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range lor.Objects {
+			keys = append(keys, object.Key)
+		}
+		if !lor.IsTruncated {
+			return keys, nil
+		}
+		marker = lor.NextMarker
+	}
+}
+
 // PutLocalFileOnOSS
 //  @receiver aliOss
 //  @param objectKey like filename need suffix，exp: oss-image.png
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"gopkg.in/ini.v1"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"y-clouds.com/tarantula/capture"
+	"y-clouds.com/tarantula/oss"
+	"y-clouds.com/tarantula/tools"
+)
+
+// reextractRow is a line of the reextract output
+type reextractRow struct {
+	Source     string    `json:"source"`
+	Channel    string    `json:"channel"`
+	Country    string    `json:"country"`
+	Asin       string    `json:"asin"`
+	CapturedAt time.Time `json:"capturedAt"`
+	OldPrice   float32   `json:"oldPrice"`
+	NewPrice   float32   `json:"newPrice"`
+	Changed    bool      `json:"changed"`
+	// ChangedFields is the fields differ from the snapshot record, exp: price, attributes.title, itemSpecifics.Brand
+	ChangedFields []string `json:"changedFields,omitempty"`
+	XPath         string   `json:"xpath"`
+	Error         string   `json:"error,omitempty"`
+	// the attributes and item specifics are written to jsonl only
+	OldAttributes    *capture.Attributes `json:"oldAttributes,omitempty"`
+	Attributes       *capture.Attributes `json:"attributes,omitempty"`
+	OldItemSpecifics map[string]string   `json:"oldItemSpecifics,omitempty"`
+	ItemSpecifics    map[string]string   `json:"itemSpecifics,omitempty"`
+}
+
+var reextractColumns = []string{"source", "channel", "country", "asin", "capturedAt", "oldPrice", "newPrice", "changed", "changedFields", "xpath", "error"}
+
+func (r reextractRow) csvRecord() []string {
+	capturedAt := ""
+	if !r.CapturedAt.IsZero() {
+		capturedAt = r.CapturedAt.Format(time.RFC3339)
+	}
+	return []string{r.Source, r.Channel, r.Country, r.Asin, capturedAt,
+		strconv.FormatFloat(float64(r.OldPrice), 'f', 2, 32), strconv.FormatFloat(float64(r.NewPrice), 'f', 2, 32),
+		strconv.FormatBool(r.Changed), strings.Join(r.ChangedFields, ";"), r.XPath, r.Error}
+}
+
+// ossFromConf read the oss configuration of the config file
+func ossFromConf(cfg *ini.File) (*oss.AliOss, error) {
+	aliOss := new(oss.AliOss)
+	if err := cfg.Section("OSS").MapTo(aliOss); err != nil {
+		return nil, err
+	}
+	return aliOss, nil
+}
+
+// isHtmlSnapshot indicates the file is a stored page source,
+// the iframe sources and the page sources of failed captures under the debug prefix are skipped
+//  @param name the relative path or object key
+//  @param debugPrefix exp: debug/
+func isHtmlSnapshot(name string, debugPrefix string) bool {
+	if strings.HasSuffix(name, "-frame.html.gz") || strings.HasPrefix(filepath.ToSlash(name), debugPrefix) {
+		return false
+	}
+	return strings.HasSuffix(name, ".html.gz") || strings.HasSuffix(name, ".html") || strings.HasSuffix(name, ".htm")
+}
+
+// snapshotRecordName is the record next to the page source, exp: xxx-page.html.gz => xxx-page.json
+func snapshotRecordName(name string) string {
+	for _, ext := range []string{".html.gz", ".html", ".htm"} {
+		if strings.HasSuffix(name, ext) {
+			return strings.TrimSuffix(name, ext) + ".json"
+		}
+	}
+	return name + ".json"
+}
+
+// reextractCommand recompute the extracted fields of stored page sources with the current selectors
+//  tarantula reextract [-c conf.ini] [-oss] -src dir-or-prefix [-channel ebay] [-format jsonl|csv] [-out file]
+//  @return int the exit code
+func reextractCommand(args []string) int {
+	fs := flag.NewFlagSet("reextract", flag.ExitOnError)
+	conf := fs.String("c", *confFile, "Snapshot tool configuration file, provides oss.")
+	fromOss := fs.Bool("oss", false, "Read the page sources from oss, src is the key prefix.")
+	src := fs.String("src", "", "Local directory or oss key prefix of page sources, exp: xxx-page.html.gz")
+	channel := fs.String("channel", "ebay", "Channel of page sources without a snapshot record.")
+	format := fs.String("format", "jsonl", "Output format, jsonl / csv.")
+	out := fs.String("out", "", "Output file, default is stdout.")
+	_ = fs.Parse(args)
+
+	if *src == "" || (*format != "jsonl" && *format != "csv") {
+		fs.Usage()
+		return 2
+	}
+
+	var cfg *ini.File
+	if _, err := os.Stat(*conf); err == nil || *fromOss {
+		cfg, err = ini.LoadSources(confLoadOptions, *conf)
+		if err != nil {
+			log.Printf("Fail to read file: %v", err)
+			return 2
+		}
+	}
+	debugPrefix := "debug/"
+	if cfg != nil {
+		if prefix := cfg.Section("Debug").Key("Prefix").String(); prefix != "" {
+			debugPrefix = strings.TrimSuffix(prefix, "/") + "/"
+		}
+	}
+
+	// list and read from local directory or oss
+	var names []string
+	read := os.ReadFile
+	if *fromOss {
+		aliOss, err := ossFromConf(cfg)
+		if err != nil {
+			log.Printf("Missing Ali oss configuration parameters: %v", err)
+			return 2
+		}
+		keys, err := aliOss.ListObjectKeys(*src)
+		if err != nil {
+			log.Printf("List oss objects error: %v", err)
+			return 2
+		}
+		for _, key := range keys {
+			if isHtmlSnapshot(key, debugPrefix) {
+				names = append(names, key)
+			}
+		}
+		read = aliOss.GetBytesFromOSS
+	} else {
+		err := filepath.Walk(*src, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			// the debug prefix is relative to the root of bucket downloaded
+			if rel, err := filepath.Rel(*src, path); err == nil && isHtmlSnapshot(rel, debugPrefix) {
+				names = append(names, path)
+			}
+			return nil
+		})
+		if err != nil {
+			log.Printf("List directory error: %v", err)
+			return 2
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Printf("Create output error: %v", err)
+			return 2
+		}
+		defer f.Close()
+		w = f
+	}
+	csvWriter := csv.NewWriter(w)
+	if *format == "csv" {
+		_ = csvWriter.Write(reextractColumns)
+	}
+
+	for _, name := range names {
+		row := reextractSnapshot(name, *channel, read)
+		if *format == "csv" {
+			_ = csvWriter.Write(row.csvRecord())
+			continue
+		}
+		line, _ := json.Marshal(row)
+		fmt.Fprintln(w, string(line))
+	}
+	csvWriter.Flush()
+	if err := csvWriter.Error(); err != nil {
+		log.Printf("Write output error: %v", err)
+		return 1
+	}
+	return 0
+}
+
+// reextractSnapshot run the extraction on a page source, compare with the values in its snapshot record
+func reextractSnapshot(name string, channel string, read func(string) ([]byte, error)) reextractRow {
+	row := reextractRow{Source: name, Channel: channel}
+	var snapshot *capture.Snapshot
+	if content, err := read(snapshotRecordName(name)); err == nil {
+		record := capture.Snapshot{}
+		if err = json.Unmarshal(content, &record); err == nil {
+			row.Channel, row.Country, row.Asin = record.Channel, record.Country, record.Asin
+			row.CapturedAt, row.OldPrice = record.CapturedAt, record.Price
+			row.OldAttributes, row.OldItemSpecifics = record.Attributes, record.ItemSpecifics
+			snapshot = &record
+		}
+	}
+
+	source, err := read(name)
+	if err == nil && strings.HasSuffix(name, ".gz") {
+		source, err = tools.GunzipBytes(source)
+	}
+	if err != nil {
+		row.Error = err.Error()
+		return row
+	}
+
+	extraction, err := capture.ExtractFromHtml(row.Channel, string(source))
+	if extraction != nil {
+		row.NewPrice, row.XPath = extraction.Price, extraction.PriceXPath
+		row.Attributes, row.ItemSpecifics = extraction.Attributes, extraction.ItemSpecifics
+	}
+	if err != nil {
+		row.Error = err.Error()
+	}
+	if snapshot != nil && extraction != nil {
+		row.ChangedFields = changedFields(*snapshot, extraction)
+		row.Changed = len(row.ChangedFields) > 0
+	}
+	return row
+}
+
+// changedFields compare the extraction with the snapshot record, exp: price, attributes.title, itemSpecifics.Brand.
+// The attributes and item specifics are compared only if the record has them, older records keep the price only.
+func changedFields(snapshot capture.Snapshot, extraction *capture.Extraction) []string {
+	var fields []string
+	if snapshot.Price != extraction.Price {
+		fields = append(fields, "price")
+	}
+	if snapshot.Attributes != nil {
+		fields = append(fields, changedKeys("attributes.", attributeValues(snapshot.Attributes), attributeValues(extraction.Attributes))...)
+	}
+	if snapshot.ItemSpecifics != nil {
+		fields = append(fields, changedKeys("itemSpecifics.", snapshot.ItemSpecifics, extraction.ItemSpecifics)...)
+	}
+	return fields
+}
+
+// attributeValues is the attributes keyed by their json names, a missing attribute is absent
+func attributeValues(attributes *capture.Attributes) map[string]string {
+	values := map[string]string{}
+	if attributes == nil {
+		return values
+	}
+	content, _ := json.Marshal(attributes)
+	var fields map[string]interface{}
+	_ = json.Unmarshal(content, &fields)
+	for name, value := range fields {
+		values[name] = fmt.Sprint(value)
+	}
+	return values
+}
+
+// changedKeys is the sorted keys added, removed or changed between the two maps, with the prefix
+func changedKeys(prefix string, oldValues map[string]string, newValues map[string]string) []string {
+	var keys []string
+	for key, value := range oldValues {
+		if newValue, ok := newValues[key]; !ok || newValue != value {
+			keys = append(keys, prefix+key)
+		}
+	}
+	for key := range newValues {
+		if _, ok := oldValues[key]; !ok {
+			keys = append(keys, prefix+key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
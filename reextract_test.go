@@ -0,0 +1,58 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"y-clouds.com/tarantula/capture"
+)
+
+func TestIsHtmlSnapshot(t *testing.T) {
+	tests := map[string]bool{
+		"ebay/US/1-page.html.gz":        true,
+		"ebay/US/1-page.html":           true,
+		"ebay/US/1-frame.html.gz":       false,
+		"debug/ebay/US/1-page.html.gz":  false,
+		"ebay/US/debug/1-page.html.gz":  true,
+		"ebay/US/1-page.json":           false,
+		"ebay/US/1.png":                 false,
+		"snapshots/1-page.htm":          true,
+		"debugging/ebay/1-page.html.gz": true,
+	}
+	for name, want := range tests {
+		if got := isHtmlSnapshot(name, "debug/"); got != want {
+			t.Errorf("isHtmlSnapshot(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestChangedFields(t *testing.T) {
+	snapshot := capture.Snapshot{
+		Price:         12.5,
+		Attributes:    &capture.Attributes{Title: "Phone", Condition: "New"},
+		ItemSpecifics: map[string]string{"Brand": "Apple", "Color": "Black"},
+	}
+
+	extraction := &capture.Extraction{
+		Price:         12.5,
+		Attributes:    &capture.Attributes{Title: "Phone", Condition: "New"},
+		ItemSpecifics: map[string]string{"Brand": "Apple", "Color": "Black"},
+	}
+	if got := changedFields(snapshot, extraction); len(got) != 0 {
+		t.Errorf("changedFields() of the same values = %v, want none", got)
+	}
+
+	extraction = &capture.Extraction{
+		Price:         13,
+		Attributes:    &capture.Attributes{Title: "Phone", Seller: "shop"},
+		ItemSpecifics: map[string]string{"Brand": "Apple", "Color": "White", "Model": "X"},
+	}
+	want := []string{"price", "attributes.condition", "attributes.seller", "itemSpecifics.Color", "itemSpecifics.Model"}
+	if got := changedFields(snapshot, extraction); !reflect.DeepEqual(got, want) {
+		t.Errorf("changedFields() = %v, want %v", got, want)
+	}
+
+	// a record of older versions has the price only
+	if got := changedFields(capture.Snapshot{Price: 12.5}, extraction); !reflect.DeepEqual(got, []string{"price"}) {
+		t.Errorf("changedFields() without recorded attributes = %v, want [price]", got)
+	}
+}
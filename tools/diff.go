@@ -0,0 +1,256 @@
+package tools
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// DiffOptions is the options of comparing two pictures
+type DiffOptions struct {
+	// Tolerance is the max channel difference of two pixels considered as the same, default 24
+	Tolerance int
+	// BlockSize is the size of blocks used to group changed pixels into regions, default 16
+	BlockSize int
+	// BandHeight is the height of bands aligned separately, default 32
+	BandHeight int
+	// MaxShift is the max vertical offset searched when aligning a band, default 400
+	MaxShift int
+	// Highlight is the color of changed pixels and region boxes, default red
+	Highlight color.Color
+}
+
+// DiffResult is the result of comparing two pictures
+type DiffResult struct {
+	// Image is the new picture faded, with changed pixels and regions highlighted
+	Image *image.RGBA
+	// Regions is the bounding boxes of changed regions, in the coordinates of the new picture
+	Regions []image.Rectangle
+	// Offsets is the vertical offsets of the old picture aligned to every band of the new picture
+	Offsets []int
+	// ChangedRatio is the ratio of changed pixels, in [0, 1]
+	ChangedRatio float64
+}
+
+func (opt DiffOptions) withDefault() DiffOptions {
+	if opt.Tolerance <= 0 {
+		opt.Tolerance = 24
+	}
+	if opt.BlockSize <= 0 {
+		opt.BlockSize = 16
+	}
+	if opt.BandHeight <= 0 {
+		opt.BandHeight = 32
+	}
+	if opt.MaxShift < 0 {
+		opt.MaxShift = 0
+	} else if opt.MaxShift == 0 {
+		opt.MaxShift = 400
+	}
+	if opt.Highlight == nil {
+		opt.Highlight = color.RGBA{R: 255, A: 255}
+	}
+	return opt
+}
+
+// rowProfile is the mean luminance of every row, used to align pictures
+func rowProfile(img image.Image) []float64 {
+	b := img.Bounds()
+	step := b.Dx()/64 + 1
+	profile := make([]float64, b.Dy())
+	for y := 0; y < b.Dy(); y++ {
+		var sum float64
+		var n int
+		for x := b.Min.X; x < b.Max.X; x += step {
+			sum += luminance(img, x, b.Min.Y+y)
+			n++
+		}
+		if n > 0 {
+			profile[y] = sum / float64(n)
+		}
+	}
+	return profile
+}
+
+// alignBands align the old picture to the new picture band by band, so that a section inserted or removed
+// in the middle of page only shifts the following bands. A row y of the new picture in band i is compared
+// with the row y-offsets[i] of the old picture.
+func alignBands(oldImg image.Image, newImg image.Image, bandHeight int, maxShift int) []int {
+	oldProfile, newProfile := rowProfile(oldImg), rowProfile(newImg)
+	offsets := make([]int, (len(newProfile)+bandHeight-1)/bandHeight)
+	last := 0
+	for i := range offsets {
+		y0 := i * bandHeight
+		y1 := y0 + bandHeight
+		if y1 > len(newProfile) {
+			y1 = len(newProfile)
+		}
+		best, bestScore := last, math.MaxFloat64
+		for shift := 0; shift <= maxShift; shift++ {
+			// search from the offset of last band outwards, the nearest offset wins a tie
+			for _, offset := range []int{last - shift, last + shift} {
+				if y0-offset < 0 || y1-offset > len(oldProfile) {
+					continue
+				}
+				var sum float64
+				for y := y0; y < y1; y++ {
+					sum += math.Abs(newProfile[y] - oldProfile[y-offset])
+				}
+				score := sum / float64(y1-y0)
+				if score < bestScore-1e-9 {
+					best, bestScore = offset, score
+				}
+			}
+		}
+		// a band not found in the old picture is changed, keep the offset for the following bands
+		if bestScore > 2 {
+			best = last
+		}
+		offsets[i] = best
+		last = best
+	}
+	return offsets
+}
+
+// pixelChanged compare two pixels channel by channel
+func pixelChanged(c1 color.Color, c2 color.Color, tolerance int) bool {
+	r1, g1, b1, a1 := c1.RGBA()
+	r2, g2, b2, a2 := c2.RGBA()
+	diff := func(v1, v2 uint32) int {
+		d := int(v1>>8) - int(v2>>8)
+		if d < 0 {
+			return -d
+		}
+		return d
+	}
+	return diff(r1, r2) > tolerance || diff(g1, g2) > tolerance || diff(b1, b2) > tolerance || diff(a1, a2) > tolerance
+}
+
+// DiffImages Compare two captures of the same item.
+// The old picture is aligned vertically to the new picture band by band, so that a section grown or shrunk
+// does not mark the whole rest of page as changed. The areas only exist in one picture are changed.
+func DiffImages(oldImg image.Image, newImg image.Image, opt DiffOptions) DiffResult {
+	opt = opt.withDefault()
+	offsets := alignBands(oldImg, newImg, opt.BandHeight, opt.MaxShift)
+
+	ob, nb := oldImg.Bounds(), newImg.Bounds()
+	width := nb.Dx()
+	if ob.Dx() > width {
+		width = ob.Dx()
+	}
+	// the old picture below the last band is compared with the blank area below the new picture
+	tailOffset := 0
+	if len(offsets) > 0 {
+		tailOffset = offsets[len(offsets)-1]
+	}
+	height := nb.Dy()
+	if ob.Dy()+tailOffset > height {
+		height = ob.Dy() + tailOffset
+	}
+
+	out := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(out, out.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(out, nb.Sub(nb.Min), newImg, nb.Min, draw.Src)
+	// fade the new picture, so that the highlight stands out
+	draw.Draw(out, out.Bounds(), image.NewUniform(color.RGBA{R: 255, G: 255, B: 255, A: 160}), image.Point{}, draw.Over)
+
+	cols, rows := (width+opt.BlockSize-1)/opt.BlockSize, (height+opt.BlockSize-1)/opt.BlockSize
+	blocks := make([]bool, cols*rows)
+	changed := 0
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			inNew := x < nb.Dx() && y < nb.Dy()
+			oy := y - tailOffset
+			if band := y / opt.BandHeight; band < len(offsets) {
+				oy = y - offsets[band]
+			}
+			inOld := x < ob.Dx() && oy >= 0 && oy < ob.Dy()
+			if inNew && inOld && !pixelChanged(newImg.At(nb.Min.X+x, nb.Min.Y+y), oldImg.At(ob.Min.X+x, ob.Min.Y+oy), opt.Tolerance) {
+				continue
+			}
+			if !inNew && !inOld {
+				continue
+			}
+			changed++
+			out.Set(x, y, opt.Highlight)
+			blocks[(y/opt.BlockSize)*cols+x/opt.BlockSize] = true
+		}
+	}
+
+	result := DiffResult{Image: out, Offsets: offsets}
+	if width*height > 0 {
+		result.ChangedRatio = float64(changed) / float64(width*height)
+	}
+	result.Regions = changedRegions(blocks, cols, rows, opt.BlockSize, out.Bounds())
+	for _, region := range result.Regions {
+		drawBox(out, region, opt.Highlight, 2)
+	}
+	return result
+}
+
+// changedRegions group the adjacent changed blocks, return their bounding boxes
+func changedRegions(blocks []bool, cols int, rows int, blockSize int, bounds image.Rectangle) []image.Rectangle {
+	visited := make([]bool, len(blocks))
+	var regions []image.Rectangle
+	for i, isChanged := range blocks {
+		if !isChanged || visited[i] {
+			continue
+		}
+		// flood fill, blocks touching by side or corner belong to the same region
+		region := image.Rectangle{}
+		stack := []int{i}
+		visited[i] = true
+		for len(stack) > 0 {
+			cur := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			cx, cy := cur%cols, cur/cols
+			block := image.Rect(cx*blockSize, cy*blockSize, (cx+1)*blockSize, (cy+1)*blockSize)
+			region = region.Union(block)
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					nx, ny := cx+dx, cy+dy
+					if nx < 0 || ny < 0 || nx >= cols || ny >= rows {
+						continue
+					}
+					next := ny*cols + nx
+					if blocks[next] && !visited[next] {
+						visited[next] = true
+						stack = append(stack, next)
+					}
+				}
+			}
+		}
+		regions = append(regions, region.Intersect(bounds))
+	}
+	return regions
+}
+
+// drawBox draw the border of rectangle
+func drawBox(img draw.Image, r image.Rectangle, c color.Color, thickness int) {
+	src := image.NewUniform(c)
+	r = r.Inset(-thickness).Intersect(img.Bounds())
+	draw.Draw(img, image.Rect(r.Min.X, r.Min.Y, r.Max.X, r.Min.Y+thickness), src, image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(r.Min.X, r.Max.Y-thickness, r.Max.X, r.Max.Y), src, image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(r.Min.X, r.Min.Y, r.Min.X+thickness, r.Max.Y), src, image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(r.Max.X-thickness, r.Min.Y, r.Max.X, r.Max.Y), src, image.Point{}, draw.Src)
+}
+
+// DiffPicsBytes Compare two pictures' byte array, return the diff picture byte array
+// imageFormat is the format of diff picture, png / jpeg
+func DiffPicsBytes(oldImg []byte, newImg []byte, opt DiffOptions, imageFormat string) ([]byte, DiffResult, error) {
+	image1, _, err := image.Decode(bytes.NewReader(oldImg))
+	if err != nil {
+		return nil, DiffResult{}, err
+	}
+	image2, _, err := image.Decode(bytes.NewReader(newImg))
+	if err != nil {
+		return nil, DiffResult{}, err
+	}
+
+	result := DiffImages(image1, image2, opt)
+	img, err := ImageToBytes(result.Image, imageFormat)
+
+	return img, result, err
+}
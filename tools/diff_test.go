@@ -0,0 +1,112 @@
+package tools
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"testing"
+)
+
+func TestDiffImagesIdentical(t *testing.T) {
+	page := testPage(300, 800)
+	result := DiffImages(page, testPage(300, 800), DiffOptions{})
+	if result.ChangedRatio != 0 || len(result.Regions) != 0 {
+		t.Errorf("DiffImages() of identical pictures = %v changed, %d regions, want none", result.ChangedRatio, len(result.Regions))
+	}
+	if result.Image.Bounds() != page.Bounds() {
+		t.Errorf("diff picture bounds = %v, want %v", result.Image.Bounds(), page.Bounds())
+	}
+}
+
+func TestDiffImagesTolerance(t *testing.T) {
+	page := testPage(300, 800)
+	noisy := testPage(300, 800)
+	// a channel difference within the tolerance is not a change
+	fillRect(noisy, image.Rect(0, 0, 300, 4), color.RGBA{R: 240, G: 240, B: 240, A: 255})
+	if result := DiffImages(page, noisy, DiffOptions{Tolerance: 24}); result.ChangedRatio != 0 {
+		t.Errorf("DiffImages() within tolerance = %v changed, want 0", result.ChangedRatio)
+	}
+	if result := DiffImages(page, noisy, DiffOptions{Tolerance: 8}); result.ChangedRatio == 0 {
+		t.Errorf("DiffImages() beyond tolerance = 0 changed, want > 0")
+	}
+}
+
+func TestDiffImagesRegion(t *testing.T) {
+	page := testPage(300, 800)
+	changed := testPage(300, 800)
+	fillRect(changed, image.Rect(200, 400, 240, 420), color.Black)
+
+	result := DiffImages(page, changed, DiffOptions{})
+	if len(result.Regions) != 1 {
+		t.Fatalf("regions = %v, want 1", result.Regions)
+	}
+	if region := result.Regions[0]; !image.Rect(200, 400, 240, 420).In(region) || region.Dx() > 80 || region.Dy() > 60 {
+		t.Errorf("region = %v, want around (200,400)-(240,420)", region)
+	}
+	if r, g, b, _ := result.Image.At(220, 410).RGBA(); r>>8 != 255 || g>>8 != 0 || b>>8 != 0 {
+		t.Errorf("changed pixel is not highlighted: %d %d %d", r>>8, g>>8, b>>8)
+	}
+}
+
+func TestDiffImagesShifted(t *testing.T) {
+	page := testPage(300, 800)
+	// a banner inserted at the top pushes the rest of page down
+	shifted := image.NewRGBA(image.Rect(0, 0, 300, 840))
+	draw.Draw(shifted, shifted.Bounds(), image.White, image.Point{}, draw.Src)
+	fillRect(shifted, image.Rect(0, 0, 300, 40), color.Black)
+	draw.Draw(shifted, image.Rect(0, 40, 300, 840), page, image.Point{}, draw.Src)
+
+	result := DiffImages(page, shifted, DiffOptions{})
+	if len(result.Regions) != 1 {
+		t.Fatalf("regions = %v, want only the banner", result.Regions)
+	}
+	if region := result.Regions[0]; region.Min.Y != 0 || region.Max.Y > 64 {
+		t.Errorf("region = %v, want the banner at the top", region)
+	}
+	if last := result.Offsets[len(result.Offsets)-1]; last != 40 {
+		t.Errorf("offset of the last band = %d, want 40", last)
+	}
+}
+
+func TestDiffImagesSizeChanged(t *testing.T) {
+	page := testPage(300, 800)
+	shorter := image.NewRGBA(image.Rect(0, 0, 300, 600))
+	draw.Draw(shorter, shorter.Bounds(), page, image.Point{}, draw.Src)
+
+	result := DiffImages(page, shorter, DiffOptions{})
+	if result.Image.Bounds().Dy() != 800 {
+		t.Errorf("diff picture height = %d, want 800", result.Image.Bounds().Dy())
+	}
+	// the tail only exists in the old picture
+	if len(result.Regions) == 0 || result.Regions[len(result.Regions)-1].Max.Y != 800 {
+		t.Errorf("regions = %v, want the removed tail", result.Regions)
+	}
+}
+
+func TestDiffPicsBytes(t *testing.T) {
+	encode := func(img image.Image) []byte {
+		buf := new(bytes.Buffer)
+		if err := png.Encode(buf, img); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	changed := testPage(100, 200)
+	fillRect(changed, image.Rect(10, 10, 20, 20), color.Black)
+
+	content, result, err := DiffPicsBytes(encode(testPage(100, 200)), encode(changed), DiffOptions{}, "png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Regions) != 1 {
+		t.Errorf("regions = %v, want 1", result.Regions)
+	}
+	if _, err := png.Decode(bytes.NewReader(content)); err != nil {
+		t.Errorf("diff picture is not png: %v", err)
+	}
+	if _, _, err := DiffPicsBytes([]byte("invalid"), encode(changed), DiffOptions{}, "png"); err == nil {
+		t.Errorf("DiffPicsBytes() of invalid bytes, want error")
+	}
+}
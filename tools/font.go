@@ -0,0 +1,44 @@
+package tools
+
+import (
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// textFace is the bitmap font of the text drawn on pictures, printable ASCII, other characters are drawn as a box
+var textFace = basicfont.Face7x13
+
+// glyphWidth and glyphHeight is the size of a character of textFace, the glyph advance and the line height
+const (
+	glyphWidth  = 7
+	glyphHeight = 13
+)
+
+// TextWidth is the width of text drawn by the bundled font
+func TextWidth(text string, scale int) int {
+	return font.MeasureString(textFace, text).Ceil() * scale
+}
+
+// DrawText draw a line of text by the bundled font, (x, y) is the top left corner of text,
+// the glyphs are scaled up by the pixel size scale
+func DrawText(img draw.Image, x int, y int, text string, scale int, c color.Color) {
+	width := TextWidth(text, 1)
+	if width == 0 {
+		return
+	}
+	mask := image.NewAlpha(image.Rect(0, 0, width, glyphHeight))
+	drawer := font.Drawer{Dst: mask, Src: image.Opaque, Face: textFace, Dot: fixed.P(0, textFace.Ascent)}
+	drawer.DrawString(text)
+	if scale > 1 {
+		scaled := image.NewAlpha(image.Rect(0, 0, width*scale, glyphHeight*scale))
+		xdraw.NearestNeighbor.Scale(scaled, scaled.Bounds(), mask, mask.Bounds(), draw.Src, nil)
+		mask = scaled
+	}
+	target := mask.Bounds().Add(image.Point{X: x, Y: y})
+	draw.DrawMask(img, target, image.NewUniform(c), image.Point{}, mask, image.Point{}, draw.Over)
+}
@@ -0,0 +1,61 @@
+package tools
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestTextWidth(t *testing.T) {
+	if got := TextWidth("", 2); got != 0 {
+		t.Errorf("TextWidth() of empty text = %d, want 0", got)
+	}
+	if got, want := TextWidth("abc", 1), 3*glyphWidth; got != want {
+		t.Errorf("TextWidth() = %d, want %d", got, want)
+	}
+	if got, want := TextWidth("abc", 3), 3*3*glyphWidth; got != want {
+		t.Errorf("TextWidth() scaled = %d, want %d", got, want)
+	}
+}
+
+// inked is the bounds of the pixels not white
+func inked(img *image.RGBA) image.Rectangle {
+	var r image.Rectangle
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+			if img.RGBAAt(x, y) != (color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}) {
+				r = r.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return r
+}
+
+func TestDrawText(t *testing.T) {
+	for _, scale := range []int{1, 2} {
+		img := image.NewRGBA(image.Rect(0, 0, 100, 60))
+		fillRect(img, img.Rect, color.White)
+		DrawText(img, 10, 20, "Hi", scale, color.Black)
+
+		r := inked(img)
+		if r.Empty() {
+			t.Fatalf("DrawText() scale %d draws nothing", scale)
+		}
+		// the glyphs stay inside the text box
+		box := image.Rect(10, 20, 10+TextWidth("Hi", scale), 20+glyphHeight*scale)
+		if !r.In(box) {
+			t.Errorf("DrawText() scale %d inked %v, want inside %v", scale, r, box)
+		}
+		if r.Dy() < 8*scale {
+			t.Errorf("DrawText() scale %d inked height %d, want the cap height", scale, r.Dy())
+		}
+	}
+
+	// the characters out of the font are drawn as a box instead of skipped
+	img := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	fillRect(img, img.Rect, color.White)
+	DrawText(img, 0, 0, "é", 1, color.Black)
+	if inked(img).Empty() {
+		t.Errorf("DrawText() of a character out of the font draws nothing")
+	}
+}
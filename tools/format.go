@@ -0,0 +1,116 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"strings"
+)
+
+// webpMaxSize is the max width and height of a webp picture
+const webpMaxSize = 16383
+
+// ImageFormat is how the picture is encoded
+type ImageFormat struct {
+	// Format is png, jpeg (jpg) or webp, default png, the webp is encoded by libwebp loaded at runtime
+	Format string
+	// Compression is the png compression level: default, none, speed or best
+	Compression string
+	// Quality is the jpeg and lossy webp quality in [1, 100], 0 is the default 80
+	Quality int
+	// Lossless indicates whether to encode the webp lossless, the quality is not supported then
+	Lossless bool
+}
+
+// pngCompressionLevels is the png compression level by name
+var pngCompressionLevels = map[string]png.CompressionLevel{
+	"":        png.DefaultCompression,
+	"default": png.DefaultCompression,
+	"none":    png.NoCompression,
+	"speed":   png.BestSpeed,
+	"best":    png.BestCompression,
+}
+
+// name is the lower-case format, jpg is jpeg
+func (f ImageFormat) name() string {
+	switch name := strings.ToLower(f.Format); name {
+	case "":
+		return "png"
+	case "jpg":
+		return "jpeg"
+	default:
+		return name
+	}
+}
+
+// Ext is the extension of object key without ".", exp: png, jpg, webp
+func (f ImageFormat) Ext() string {
+	if name := f.name(); name != "jpeg" {
+		return name
+	}
+	return "jpg"
+}
+
+// quality is the jpeg and lossy webp quality, default 80
+func (f ImageFormat) quality() int {
+	if f.Quality == 0 {
+		return 80
+	}
+	return f.Quality
+}
+
+// Validate check the format and its options are supported
+func (f ImageFormat) Validate() error {
+	switch f.name() {
+	case "png":
+		if _, ok := pngCompressionLevels[strings.ToLower(f.Compression)]; !ok {
+			return fmt.Errorf("unsupported png compression: %s", f.Compression)
+		}
+	case "jpeg", "webp":
+		if f.Quality < 0 || f.Quality > 100 {
+			return fmt.Errorf("%s quality should be in [1, 100], or 0 for the default 80: %d", f.name(), f.Quality)
+		}
+	default:
+		return fmt.Errorf("unsupported image format: %s", f.Format)
+	}
+	if f.Lossless && (f.name() != "webp" || f.Quality != 0) {
+		return fmt.Errorf("lossless is a webp option without quality: %s quality %d", f.name(), f.Quality)
+	}
+	if f.name() == "webp" {
+		return loadWebP()
+	}
+	return nil
+}
+
+// EncodeImage encode the picture in the format
+//  @return error if the format or its options are not supported
+func EncodeImage(img image.Image, format ImageFormat) ([]byte, error) {
+	if err := format.Validate(); err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	var err error
+	switch format.name() {
+	case "png":
+		encoder := png.Encoder{CompressionLevel: pngCompressionLevels[strings.ToLower(format.Compression)]}
+		err = encoder.Encode(buf, img)
+	case "jpeg":
+		err = jpeg.Encode(buf, img, &jpeg.Options{Quality: format.quality()})
+	case "webp":
+		return EncodeWebP(img, format.quality(), format.Lossless)
+	}
+
+	return buf.Bytes(), err
+}
+
+// EncodeBytes decode the picture, then encode it in the format
+func EncodeBytes(imgBytes []byte, format ImageFormat) ([]byte, error) {
+	img, _, err := image.Decode(bytes.NewReader(imgBytes))
+	if err != nil {
+		return nil, err
+	}
+	return EncodeImage(img, format)
+}
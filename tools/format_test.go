@@ -0,0 +1,247 @@
+package tools
+
+import (
+	"bytes"
+	"golang.org/x/image/webp"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestImageFormatExt(t *testing.T) {
+	tests := map[string]string{"": "png", "PNG": "png", "jpg": "jpg", "JPEG": "jpg", "webp": "webp"}
+	for format, want := range tests {
+		if got := (ImageFormat{Format: format}).Ext(); got != want {
+			t.Errorf("Ext() of %q = %s, want %s", format, got, want)
+		}
+	}
+}
+
+func TestImageFormatValidate(t *testing.T) {
+	valid := []ImageFormat{
+		{},
+		{Format: "png", Compression: "Best"},
+		{Format: "jpeg"},
+		{Format: "jpg", Quality: 1},
+		{Format: "jpeg", Quality: 100},
+	}
+	if loadWebP() == nil {
+		valid = append(valid, ImageFormat{Format: "webp"}, ImageFormat{Format: "webp", Quality: 50},
+			ImageFormat{Format: "WEBP", Lossless: true})
+	}
+	for _, format := range valid {
+		if err := format.Validate(); err != nil {
+			t.Errorf("Validate(%+v) = %v", format, err)
+		}
+	}
+	invalid := []ImageFormat{
+		{Format: "gif"},
+		{Format: "png", Compression: "fast"},
+		{Format: "jpeg", Quality: -1},
+		{Format: "jpeg", Quality: 101},
+		{Format: "webp", Quality: 101},
+		// the lossless webp has no quality
+		{Format: "webp", Quality: 80, Lossless: true},
+		{Format: "png", Lossless: true},
+	}
+	for _, format := range invalid {
+		if err := format.Validate(); err == nil {
+			t.Errorf("Validate(%+v), want error", format)
+		}
+	}
+}
+
+// testPicture is a screenshot-like picture: flat background, text-like lines, a gradient and noise
+func testPicture(w, h int, alpha bool) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	rnd := rand.New(rand.NewSource(1))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.NRGBA{R: 250, G: 250, B: 250, A: 255}
+			switch {
+			case y%16 < 10 && x%7 < 4 && x < w*2/3:
+				c = color.NRGBA{R: 30, G: 30, B: 60, A: 255}
+			case x >= w*2/3 && y < h/2:
+				c = color.NRGBA{R: uint8(x * 3), G: uint8(y * 5), B: uint8(x + y), A: 255}
+			case x >= w*2/3:
+				c = color.NRGBA{R: uint8(rnd.Intn(256)), G: uint8(rnd.Intn(256)), B: uint8(rnd.Intn(256)), A: 255}
+			}
+			if alpha {
+				c.A = uint8((x + y) % 256)
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// samePixels compare the pixels of the pictures as non-premultiplied colors
+func samePixels(t *testing.T, got image.Image, want *image.NRGBA) {
+	t.Helper()
+	if got.Bounds().Size() != want.Bounds().Size() {
+		t.Fatalf("size = %v, want %v", got.Bounds().Size(), want.Bounds().Size())
+	}
+	b := got.Bounds()
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			g := color.NRGBAModel.Convert(got.At(b.Min.X+x, b.Min.Y+y)).(color.NRGBA)
+			w := want.NRGBAAt(x, y)
+			if w.A == 0 {
+				// the color of a transparent pixel is not kept by the conversions
+				g.R, g.G, g.B, w.R, w.G, w.B = 0, 0, 0, 0, 0, 0
+			}
+			if g != w {
+				t.Fatalf("pixel (%d, %d) = %v, want %v", x, y, g, w)
+			}
+		}
+	}
+}
+
+// requireWebP skip the test if libwebp is not installed
+func requireWebP(t *testing.T) {
+	t.Helper()
+	if err := loadWebP(); err != nil {
+		t.Skip(err)
+	}
+}
+
+// meanLumaDiff is the mean absolute difference of the luma of the lossy webp and the picture in the region r,
+// the webp keeps the limited range luma of BT.601, which x/image/webp returns as is
+func meanLumaDiff(got *image.YCbCr, want *image.NRGBA, r image.Rectangle) float64 {
+	var sum, n float64
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c := want.NRGBAAt(x, y)
+			luma := 16 + (0.257*float64(c.R) + 0.504*float64(c.G) + 0.098*float64(c.B))
+			sum += math.Abs(float64(got.Y[got.YOffset(x, y)]) - luma)
+			n++
+		}
+	}
+	return sum / n
+}
+
+func TestEncodeWebPLossless(t *testing.T) {
+	requireWebP(t)
+	tests := []struct {
+		w, h  int
+		alpha bool
+	}{
+		{1, 1, false},
+		{3, 2, false},
+		{33, 65, false},
+		{97, 41, true},
+		{640, 480, false},
+	}
+	for _, tt := range tests {
+		want := testPicture(tt.w, tt.h, tt.alpha)
+		content, err := EncodeImage(want, ImageFormat{Format: "webp", Lossless: true})
+		if err != nil {
+			t.Fatalf("EncodeImage(%dx%d) error: %v", tt.w, tt.h, err)
+		}
+		got, err := webp.Decode(bytes.NewReader(content))
+		if err != nil {
+			t.Fatalf("webp.Decode(%dx%d) error: %v", tt.w, tt.h, err)
+		}
+		samePixels(t, got, want)
+
+		config, format, err := image.DecodeConfig(bytes.NewReader(content))
+		if err != nil || format != "webp" || config.Width != tt.w || config.Height != tt.h {
+			t.Errorf("DecodeConfig(%dx%d) = %+v, %s, %v", tt.w, tt.h, config, format, err)
+		}
+	}
+}
+
+func TestEncodeWebPLossy(t *testing.T) {
+	requireWebP(t)
+	want := testPicture(640, 480, false)
+	lossless, err := EncodeWebP(want, 0, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw := 640 * 480 * 4; len(lossless) >= raw/2 {
+		t.Errorf("lossless webp of %d bytes, want less than half of the %d raw bytes", len(lossless), raw)
+	}
+
+	var sizes []int
+	var diffs []float64
+	for _, quality := range []int{10, 50, 90} {
+		content, err := EncodeImage(want, ImageFormat{Format: "webp", Quality: quality})
+		if err != nil {
+			t.Fatalf("EncodeImage() of quality %d error: %v", quality, err)
+		}
+		got, err := webp.Decode(bytes.NewReader(content))
+		if err != nil {
+			t.Fatalf("webp.Decode() of quality %d error: %v", quality, err)
+		}
+		ycbcr, ok := got.(*image.YCbCr)
+		if !ok || ycbcr.Bounds().Size() != want.Bounds().Size() {
+			t.Fatalf("webp.Decode() of quality %d = %T %v, want a 640x480 lossy picture", quality, got, got.Bounds())
+		}
+		// the flat background and the text-like lines are kept, the noise is lost
+		diffs = append(diffs, meanLumaDiff(ycbcr, want, image.Rect(0, 0, 640*2/3, 480)))
+		sizes = append(sizes, len(content))
+	}
+	if diffs[2] > 1.5 || diffs[0] > 10 || diffs[2] >= diffs[0] {
+		t.Errorf("mean luma differences of quality 10, 50, 90 = %.2f, want at most 1.5 and falling with the quality", diffs)
+	}
+	if sizes[0] >= sizes[1] || sizes[1] >= sizes[2] {
+		t.Errorf("sizes of quality 10, 50, 90 = %v, want growing with the quality", sizes)
+	}
+	if sizes[1] >= len(lossless) {
+		t.Errorf("webp of quality 50 = %d bytes, want less than %d bytes lossless", sizes[1], len(lossless))
+	}
+
+	if _, err = EncodeWebP(image.NewNRGBA(image.Rect(0, 0, 0, 10)), 80, false); err == nil {
+		t.Errorf("EncodeWebP() of an empty picture, want error")
+	}
+	if _, err = EncodeWebP(image.NewNRGBA(image.Rect(0, 0, webpMaxSize+1, 1)), 80, false); err == nil {
+		t.Errorf("EncodeWebP() of a picture too wide, want error")
+	}
+}
+
+func TestEncodeImage(t *testing.T) {
+	want := testPicture(120, 80, false)
+
+	content, err := EncodeImage(want, ImageFormat{Format: "png", Compression: "speed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := png.Decode(bytes.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	samePixels(t, got, want)
+
+	content, err = EncodeImage(want, ImageFormat{Format: "jpg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = jpeg.Decode(bytes.NewReader(content)); err != nil {
+		t.Errorf("jpeg.Decode() error: %v", err)
+	}
+	better, _ := EncodeImage(want, ImageFormat{Format: "jpeg", Quality: 100})
+	if len(better) <= len(content) {
+		t.Errorf("jpeg of quality 100 = %d bytes, want more than %d of the default quality", len(better), len(content))
+	}
+
+	if _, err = EncodeImage(want, ImageFormat{Format: "webp", Quality: 50, Lossless: true}); err == nil {
+		t.Errorf("EncodeImage() of a lossless webp with quality, want error")
+	}
+
+	// EncodeBytes decode the png first
+	requireWebP(t)
+	pngBytes, _ := EncodeImage(want, ImageFormat{})
+	content, err = EncodeBytes(pngBytes, ImageFormat{Format: "webp", Lossless: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = webp.Decode(bytes.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	samePixels(t, got, want)
+}
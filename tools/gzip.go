@@ -0,0 +1,31 @@
+package tools
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+)
+
+// GzipBytes compress the content by gzip
+func GzipBytes(content []byte) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	zw := gzip.NewWriter(buf)
+	if _, err := zw.Write(content); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GunzipBytes decompress the gzip content
+func GunzipBytes(content []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	return io.ReadAll(zr)
+}
@@ -0,0 +1,200 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	xdraw "golang.org/x/image/draw"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// LayoutOptions is how the images are composed into one
+type LayoutOptions struct {
+	// Direction is column (top to bottom), row (left to right) or grid, default column
+	Direction string
+	// Columns is the number of columns of grid, default the square root of the number of images
+	Columns int
+	// Align is the alignment of an image narrower (column) or lower (row) than its cell, start / center / end, default start
+	Align string
+	// Padding is the space around and between the images
+	Padding int
+	// Background color of the space not covered by images, default white
+	Background color.Color
+	// Captions is the caption drawn above every image, an empty caption is skipped
+	Captions []string
+	// CaptionScale is the pixel size of the bundled font of captions, default 1
+	CaptionScale int
+	// CaptionColor is the color of captions, default black
+	CaptionColor color.Color
+	// MaxWidth and MaxHeight scale the output down to fit, keeping the aspect ratio, 0 is unlimited
+	MaxWidth  int
+	MaxHeight int
+}
+
+func (opt LayoutOptions) withDefault(n int) (LayoutOptions, error) {
+	switch opt.Direction {
+	case "":
+		opt.Direction = "column"
+	case "column", "row":
+	case "grid":
+		if opt.Columns <= 0 {
+			opt.Columns = int(math.Ceil(math.Sqrt(float64(n))))
+		}
+	default:
+		return opt, fmt.Errorf("unsupported layout direction: %s", opt.Direction)
+	}
+	switch opt.Align {
+	case "":
+		opt.Align = "start"
+	case "start", "center", "end":
+	default:
+		return opt, fmt.Errorf("unsupported layout align: %s", opt.Align)
+	}
+	if opt.Padding < 0 {
+		opt.Padding = 0
+	}
+	if opt.Background == nil {
+		opt.Background = color.White
+	}
+	if opt.CaptionScale <= 0 {
+		opt.CaptionScale = 1
+	}
+	if opt.CaptionColor == nil {
+		opt.CaptionColor = color.Black
+	}
+	return opt, nil
+}
+
+// Validate check the direction and align of options
+func (opt LayoutOptions) Validate() error {
+	if opt.Columns < 0 || opt.MaxWidth < 0 || opt.MaxHeight < 0 {
+		return fmt.Errorf("layout columns, max width and max height must not be negative")
+	}
+	_, err := opt.withDefault(1)
+	return err
+}
+
+// captionHeight is the height of the caption of image i, with a line of space under it
+func (opt LayoutOptions) captionHeight(i int) int {
+	if i >= len(opt.Captions) || opt.Captions[i] == "" {
+		return 0
+	}
+	return glyphHeight*opt.CaptionScale + opt.CaptionScale*4
+}
+
+// alignOffset is the offset of a size in the space of cell
+func alignOffset(align string, space int, size int) int {
+	switch align {
+	case "center":
+		return (space - size) / 2
+	case "end":
+		return space - size
+	}
+	return 0
+}
+
+// Layout compose the images in a column, row or grid
+func Layout(images []image.Image, opt LayoutOptions) (*image.RGBA, error) {
+	if len(images) == 0 {
+		return nil, fmt.Errorf("no image to layout")
+	}
+	opt, err := opt.withDefault(len(images))
+	if err != nil {
+		return nil, err
+	}
+
+	// a column is a grid of 1 column, a row is a grid of 1 row
+	columns := opt.Columns
+	switch opt.Direction {
+	case "column":
+		columns = 1
+	case "row":
+		columns = len(images)
+	}
+	rows := (len(images) + columns - 1) / columns
+
+	// the cell size is the widest image of the column, and the highest image with caption of the row
+	widths := make([]int, columns)
+	heights := make([]int, rows)
+	for i, img := range images {
+		col, row := i%columns, i/columns
+		if w := img.Bounds().Dx(); w > widths[col] {
+			widths[col] = w
+		}
+		if w := TextWidth(captionAt(opt.Captions, i), opt.CaptionScale); w > widths[col] {
+			widths[col] = w
+		}
+		if h := img.Bounds().Dy() + opt.captionHeight(i); h > heights[row] {
+			heights[row] = h
+		}
+	}
+	width, height := opt.Padding, opt.Padding
+	for _, w := range widths {
+		width += w + opt.Padding
+	}
+	for _, h := range heights {
+		height += h + opt.Padding
+	}
+
+	canvas := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(canvas, canvas.Bounds(), image.NewUniform(opt.Background), image.Point{}, draw.Src)
+	y := opt.Padding
+	for row := 0; row < rows; row++ {
+		x := opt.Padding
+		for col := 0; col < columns; col++ {
+			i := row*columns + col
+			if i >= len(images) {
+				break
+			}
+			img := images[i]
+			captionH := opt.captionHeight(i)
+			if captionH > 0 {
+				DrawText(canvas, x, y, opt.Captions[i], opt.CaptionScale, opt.CaptionColor)
+			}
+			// a column aligns the images horizontally, a row vertically, a grid both
+			dx, dy := 0, 0
+			if opt.Direction != "row" {
+				dx = alignOffset(opt.Align, widths[col], img.Bounds().Dx())
+			}
+			if opt.Direction != "column" {
+				dy = alignOffset(opt.Align, heights[row]-captionH, img.Bounds().Dy())
+			}
+			target := image.Rect(x+dx, y+captionH+dy, x+dx+img.Bounds().Dx(), y+captionH+dy+img.Bounds().Dy())
+			draw.Draw(canvas, target, img, img.Bounds().Min, draw.Over)
+			x += widths[col] + opt.Padding
+		}
+		y += heights[row] + opt.Padding
+	}
+
+	if w, h := FitSize(width, height, opt.MaxWidth, opt.MaxHeight); w != width || h != height {
+		return Resize(canvas, w, h, xdraw.BiLinear), nil
+	}
+	return canvas, nil
+}
+
+func captionAt(captions []string, i int) string {
+	if i < len(captions) {
+		return captions[i]
+	}
+	return ""
+}
+
+// LayoutBytes decode the images and compose them
+// imageFormat is the format of output image, png / jpeg
+func LayoutBytes(images [][]byte, opt LayoutOptions, imageFormat string) ([]byte, error) {
+	decoded := make([]image.Image, 0, len(images))
+	for i, content := range images {
+		img, _, err := image.Decode(bytes.NewReader(content))
+		if err != nil {
+			return nil, fmt.Errorf("decode image %d: %v", i, err)
+		}
+		decoded = append(decoded, img)
+	}
+	canvas, err := Layout(decoded, opt)
+	if err != nil {
+		return nil, err
+	}
+	return ImageToBytes(canvas, imageFormat)
+}
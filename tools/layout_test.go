@@ -0,0 +1,97 @@
+package tools
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// solid is an image of one color
+func solid(w, h int, c color.Color) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	fillRect(img, img.Bounds(), c)
+	return img
+}
+
+func TestLayoutSize(t *testing.T) {
+	red, green, blue := color.RGBA{R: 255, A: 255}, color.RGBA{G: 255, A: 255}, color.RGBA{B: 255, A: 255}
+	images := []image.Image{solid(100, 50, red), solid(60, 30, green), solid(80, 40, blue)}
+	tests := []struct {
+		opt  LayoutOptions
+		w, h int
+	}{
+		{LayoutOptions{}, 100, 120},
+		{LayoutOptions{Direction: "row"}, 240, 50},
+		{LayoutOptions{Direction: "grid", Columns: 2}, 160, 90},
+		// the default columns of grid is the square root of the number of images
+		{LayoutOptions{Direction: "grid"}, 160, 90},
+		{LayoutOptions{Direction: "column", Padding: 10}, 120, 160},
+		{LayoutOptions{Direction: "row", MaxWidth: 120}, 120, 25},
+	}
+	for _, tt := range tests {
+		canvas, err := Layout(images, tt.opt)
+		if err != nil {
+			t.Errorf("Layout(%+v) error: %v", tt.opt, err)
+			continue
+		}
+		if w, h := canvas.Bounds().Dx(), canvas.Bounds().Dy(); w != tt.w || h != tt.h {
+			t.Errorf("Layout(%+v) = %dx%d, want %dx%d", tt.opt, w, h, tt.w, tt.h)
+		}
+	}
+}
+
+func TestLayoutAlign(t *testing.T) {
+	red, green := color.RGBA{R: 255, A: 255}, color.RGBA{G: 255, A: 255}
+	images := []image.Image{solid(100, 20, red), solid(40, 20, green)}
+	tests := []struct {
+		align string
+		x     int
+	}{
+		{"start", 0},
+		{"center", 30},
+		{"end", 60},
+	}
+	for _, tt := range tests {
+		canvas, err := Layout(images, LayoutOptions{Align: tt.align})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the narrower image of a column is aligned horizontally, the space is the background
+		if got := canvas.RGBAAt(tt.x, 25); got != green {
+			t.Errorf("align %s: pixel at %d = %v, want green", tt.align, tt.x, got)
+		}
+		if tt.x > 0 && canvas.RGBAAt(tt.x-1, 25) != (color.RGBA{R: 255, G: 255, B: 255, A: 255}) {
+			t.Errorf("align %s: pixel at %d = %v, want background", tt.align, tt.x-1, canvas.RGBAAt(tt.x-1, 25))
+		}
+	}
+}
+
+func TestLayoutCaptions(t *testing.T) {
+	images := []image.Image{solid(100, 20, color.Black), solid(100, 20, color.Black)}
+	canvas, err := Layout(images, LayoutOptions{Captions: []string{"Item", ""}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the first image has a caption above it, the second has none
+	if want := 40 + glyphHeight + 4; canvas.Bounds().Dy() != want {
+		t.Errorf("Layout() with a caption height = %d, want %d", canvas.Bounds().Dy(), want)
+	}
+}
+
+func TestLayoutOptionsValidate(t *testing.T) {
+	valid := []LayoutOptions{{}, {Direction: "row", Align: "end"}, {Direction: "grid", Columns: 3, Align: "center"}}
+	for _, opt := range valid {
+		if err := opt.Validate(); err != nil {
+			t.Errorf("Validate(%+v) = %v", opt, err)
+		}
+	}
+	invalid := []LayoutOptions{{Direction: "columns"}, {Align: "middle"}, {Direction: "grid", Columns: -1}, {MaxWidth: -1}}
+	for _, opt := range invalid {
+		if err := opt.Validate(); err == nil {
+			t.Errorf("Validate(%+v), want error", opt)
+		}
+	}
+	if _, err := Layout(nil, LayoutOptions{}); err == nil {
+		t.Errorf("Layout() of no image, want error")
+	}
+}
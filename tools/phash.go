@@ -0,0 +1,94 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"math/bits"
+	"strconv"
+)
+
+// grayScale reduce the image to a width*height grayscale matrix, every cell is the mean luminance of its area
+func grayScale(img image.Image, width int, height int) [][]float64 {
+	b := img.Bounds()
+	cells := make([][]float64, height)
+	for y := 0; y < height; y++ {
+		cells[y] = make([]float64, width)
+		y0 := b.Min.Y + y*b.Dy()/height
+		y1 := b.Min.Y + (y+1)*b.Dy()/height
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0 := b.Min.X + x*b.Dx()/width
+			x1 := b.Min.X + (x+1)*b.Dx()/width
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			// sample at most 16x16 points of the area, a full page screenshot is too large to visit every pixel
+			stepX, stepY := (x1-x0+15)/16, (y1-y0+15)/16
+			var sum float64
+			var n int
+			for py := y0; py < y1; py += stepY {
+				for px := x0; px < x1; px += stepX {
+					sum += luminance(img, px, py)
+					n++
+				}
+			}
+			cells[y][x] = sum / float64(n)
+		}
+	}
+	return cells
+}
+
+// luminance of the pixel, in [0, 255]
+func luminance(img image.Image, x int, y int) float64 {
+	r, g, b, _ := img.At(x, y).RGBA()
+	return (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / 257
+}
+
+// DHash is the difference hash of image: the image is reduced to 9x8 grayscale,
+// every bit indicates whether a cell is brighter than its right neighbour.
+// Visually similar images have hashes with a small hamming distance.
+func DHash(img image.Image) uint64 {
+	cells := grayScale(img, 9, 8)
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if cells[y][x] > cells[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// DHashBytes is the difference hash of picture byte array
+func DHashBytes(imgBytes []byte) (uint64, error) {
+	img, _, err := image.Decode(bytes.NewReader(imgBytes))
+	if err != nil {
+		return 0, err
+	}
+	return DHash(img), nil
+}
+
+// HammingDistance is the number of different bits of two hashes, in [0, 64]
+func HammingDistance(hash1 uint64, hash2 uint64) int {
+	return bits.OnesCount64(hash1 ^ hash2)
+}
+
+// HashDifference is the visual difference score of two hashes, in [0, 1], 0 means visually identical
+func HashDifference(hash1 uint64, hash2 uint64) float64 {
+	return float64(HammingDistance(hash1, hash2)) / 64
+}
+
+// FormatHash format hash as 16 hex characters
+func FormatHash(hash uint64) string {
+	return fmt.Sprintf("%016x", hash)
+}
+
+// ParseHash parse hash formatted by FormatHash
+func ParseHash(s string) (uint64, error) {
+	return strconv.ParseUint(s, 16, 64)
+}
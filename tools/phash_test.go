@@ -0,0 +1,93 @@
+package tools
+
+import (
+	"bytes"
+	xdraw "golang.org/x/image/draw"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"testing"
+)
+
+// testPage draw a page of horizontal text-like lines, every line differs by its row, so that rows can be aligned
+func testPage(width int, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	for y := 8; y+12 < height; y += 24 {
+		length := width/4 + (y*37)%(width/2)
+		shade := uint8(y * 13 % 160)
+		fillRect(img, image.Rect(16, y, 16+length, y+12), color.RGBA{R: shade, G: shade / 2, B: 80, A: 255})
+	}
+	return img
+}
+
+func fillRect(img draw.Image, r image.Rectangle, c color.Color) {
+	draw.Draw(img, r, image.NewUniform(c), image.Point{}, draw.Src)
+}
+
+func TestDHashSimilar(t *testing.T) {
+	page := testPage(400, 1200)
+
+	// a small change keeps the hash close
+	changed := testPage(400, 1200)
+	fillRect(changed, image.Rect(300, 500, 330, 512), color.Black)
+	if d := HammingDistance(DHash(page), DHash(changed)); d > 4 {
+		t.Errorf("distance of a small change = %d, want <= 4", d)
+	}
+
+	// the same picture scaled has a close hash
+	scaled := Resize(page, 200, 600, xdraw.BiLinear)
+	if d := HammingDistance(DHash(page), DHash(scaled)); d > 4 {
+		t.Errorf("distance of a scaled picture = %d, want <= 4", d)
+	}
+}
+
+func TestDHashDifferent(t *testing.T) {
+	page := testPage(400, 1200)
+
+	// the right side of page is covered by dark blocks
+	other := testPage(400, 1200)
+	for y := 0; y < 1200; y += 50 {
+		fillRect(other, image.Rect(200+y/8, y, 400, y+40), color.Black)
+	}
+	if d := HammingDistance(DHash(page), DHash(other)); d < 10 {
+		t.Errorf("distance of a different picture = %d, want >= 10", d)
+	}
+	if diff := HashDifference(DHash(page), DHash(other)); diff <= 0 || diff > 1 {
+		t.Errorf("HashDifference() = %v, want in (0, 1]", diff)
+	}
+}
+
+func TestDHashBytes(t *testing.T) {
+	page := testPage(200, 300)
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, page); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := DHashBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != DHash(page) {
+		t.Errorf("DHashBytes() = %x, want %x", hash, DHash(page))
+	}
+	if _, err := DHashBytes([]byte("not a picture")); err == nil {
+		t.Errorf("DHashBytes() of invalid bytes, want error")
+	}
+}
+
+func TestFormatParseHash(t *testing.T) {
+	for _, hash := range []uint64{0, 1, 0xdeadbeef, 1<<64 - 1} {
+		s := FormatHash(hash)
+		if len(s) != 16 {
+			t.Errorf("FormatHash(%x) = %q, want 16 characters", hash, s)
+		}
+		if got, err := ParseHash(s); err != nil || got != hash {
+			t.Errorf("ParseHash(%q) = %x, %v, want %x", s, got, err, hash)
+		}
+	}
+	if _, err := ParseHash("xyz"); err == nil {
+		t.Errorf("ParseHash() of invalid hash, want error")
+	}
+}
@@ -102,21 +102,12 @@ func ImageReadToBytes(imagePath string) ([]byte, error) {
 }
 
 // ImageToBytes image.Image to []byte
+// imageFormat is png, jpeg or webp with the default options, see EncodeImage
 func ImageToBytes(img image.Image, imageFormat string) ([]byte, error) {
-	buf := new(bytes.Buffer)
-	// png / jpeg
-	var err = error(nil)
-	if imageFormat == "png" {
-		err = png.Encode(buf, img)
-	}
-
-	if imageFormat == "jpeg" {
-		var opt jpeg.Options
-		opt.Quality = 80
-		err = jpeg.Encode(buf, img, &opt)
+	if imageFormat == "" {
+		return nil, fmt.Errorf("unsupported image format: %s", imageFormat)
 	}
-
-	return buf.Bytes(), err
+	return EncodeImage(img, ImageFormat{Format: imageFormat})
 }
 
 // BytesSaveToImageFile save []byte to local image file
@@ -0,0 +1,58 @@
+package tools
+
+import (
+	"fmt"
+	xdraw "golang.org/x/image/draw"
+	"image"
+	"strings"
+)
+
+// FitSize is the size of width x height scaled down to fit in maxWidth x maxHeight, keeping the aspect ratio,
+// a max of 0 is unlimited
+func FitSize(width int, height int, maxWidth int, maxHeight int) (int, int) {
+	scale := 1.0
+	if maxWidth > 0 && width > maxWidth {
+		scale = float64(maxWidth) / float64(width)
+	}
+	if maxHeight > 0 && float64(height)*scale > float64(maxHeight) {
+		scale = float64(maxHeight) / float64(height)
+	}
+	if scale == 1.0 {
+		return width, height
+	}
+	w, h := int(float64(width)*scale+0.5), int(float64(height)*scale+0.5)
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+	return w, h
+}
+
+// ResampleFilterByName get the interpolator of x/image/draw by name: nearest, approxbilinear, bilinear or catmullrom,
+// default catmullrom. The kernels of bilinear and catmullrom are stretched over the source pixels when scaling down,
+// so that the text of screenshots is averaged instead of skipped
+func ResampleFilterByName(name string) (xdraw.Interpolator, error) {
+	switch strings.ToLower(name) {
+	case "nearest":
+		return xdraw.NearestNeighbor, nil
+	case "approxbilinear":
+		return xdraw.ApproxBiLinear, nil
+	case "linear", "bilinear":
+		return xdraw.BiLinear, nil
+	case "", "catmullrom", "bicubic":
+		return xdraw.CatmullRom, nil
+	}
+	return nil, fmt.Errorf("unsupported resample filter: %s", name)
+}
+
+// Resize scale the image to width x height by the interpolator, exp: xdraw.CatmullRom
+func Resize(img image.Image, width int, height int, filter xdraw.Interpolator) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	if width <= 0 || height <= 0 || img.Bounds().Empty() {
+		return dst
+	}
+	filter.Scale(dst, dst.Bounds(), img, img.Bounds(), xdraw.Src, nil)
+	return dst
+}
@@ -0,0 +1,102 @@
+package tools
+
+import (
+	xdraw "golang.org/x/image/draw"
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestResizeAverage(t *testing.T) {
+	// a checkerboard of pixels scaled by half is the average gray with the stretched kernels
+	board := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			if (x+y)%2 == 0 {
+				board.SetRGBA(x, y, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+			} else {
+				board.SetRGBA(x, y, color.RGBA{A: 255})
+			}
+		}
+	}
+	for _, filter := range []xdraw.Interpolator{xdraw.BiLinear, xdraw.CatmullRom} {
+		got := Resize(board, 4, 4, filter)
+		for y := 1; y < 3; y++ {
+			for x := 1; x < 3; x++ {
+				if c := got.RGBAAt(x, y); c.R < 120 || c.R > 136 || c.A != 255 {
+					t.Fatalf("Resize() of checkerboard at (%d, %d) = %v, want gray about 128", x, y, c)
+				}
+			}
+		}
+	}
+}
+
+func TestResizeSource(t *testing.T) {
+	// a non-RGBA sub-image of uniform color keeps the color with every filter, scaled down or up
+	nrgba := image.NewNRGBA(image.Rect(0, 0, 120, 90))
+	draw.Draw(nrgba, nrgba.Bounds(), image.NewUniform(color.NRGBA{R: 200, G: 100, B: 50, A: 255}), image.Point{}, draw.Src)
+	draw.Draw(nrgba, image.Rect(0, 0, 20, 90), image.NewUniform(color.Black), image.Point{}, draw.Src)
+	sub := nrgba.SubImage(image.Rect(20, 10, 100, 70))
+	want := color.RGBA{R: 200, G: 100, B: 50, A: 255}
+	for _, name := range []string{"nearest", "approxbilinear", "bilinear", "catmullrom"} {
+		filter, _ := ResampleFilterByName(name)
+		for _, size := range [][2]int{{40, 30}, {160, 120}, {13, 7}} {
+			got := Resize(sub, size[0], size[1], filter)
+			if got.Bounds() != image.Rect(0, 0, size[0], size[1]) {
+				t.Fatalf("Resize(%dx%d, %s) = %v", size[0], size[1], name, got.Bounds())
+			}
+			for y := 0; y < size[1]; y++ {
+				for x := 0; x < size[0]; x++ {
+					if c := got.RGBAAt(x, y); c != want {
+						t.Fatalf("Resize(%dx%d, %s) at (%d, %d) = %v, want %v", size[0], size[1], name, x, y, c, want)
+					}
+				}
+			}
+		}
+	}
+
+	if got := Resize(sub, 0, 10, xdraw.CatmullRom); !got.Bounds().Empty() {
+		t.Errorf("Resize() to width 0 = %v, want empty", got.Bounds())
+	}
+}
+
+func TestFitSize(t *testing.T) {
+	tests := []struct {
+		w, h, maxW, maxH, wantW, wantH int
+	}{
+		{1000, 3000, 0, 0, 1000, 3000},
+		{1000, 3000, 500, 0, 500, 1500},
+		{1000, 3000, 0, 1500, 500, 1500},
+		{1000, 3000, 800, 1200, 400, 1200},
+		{400, 300, 800, 600, 400, 300},
+		{5000, 1, 100, 0, 100, 1},
+	}
+	for _, tt := range tests {
+		if w, h := FitSize(tt.w, tt.h, tt.maxW, tt.maxH); w != tt.wantW || h != tt.wantH {
+			t.Errorf("FitSize(%d, %d, %d, %d) = %d, %d, want %d, %d", tt.w, tt.h, tt.maxW, tt.maxH, w, h, tt.wantW, tt.wantH)
+		}
+	}
+}
+
+func TestResampleFilterByName(t *testing.T) {
+	tests := map[string]xdraw.Interpolator{
+		"":               xdraw.CatmullRom,
+		"CatmullRom":     xdraw.CatmullRom,
+		"bicubic":        xdraw.CatmullRom,
+		"linear":         xdraw.BiLinear,
+		"BILINEAR":       xdraw.BiLinear,
+		"approxbilinear": xdraw.ApproxBiLinear,
+		"nearest":        xdraw.NearestNeighbor,
+	}
+	for name, want := range tests {
+		if got, err := ResampleFilterByName(name); err != nil || got != want {
+			t.Errorf("ResampleFilterByName(%q) = %v, %v", name, got, err)
+		}
+	}
+	for _, name := range []string{"box", "lanczos"} {
+		if _, err := ResampleFilterByName(name); err == nil {
+			t.Errorf("ResampleFilterByName(%s), want error", name)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package tools
+
+import (
+	"bytes"
+	xdraw "golang.org/x/image/draw"
+	"image"
+)
+
+// Thumbnail is a downscaled picture
+type Thumbnail struct {
+	Width  int
+	Height int
+	// Bytes is the picture encoded in the format
+	Bytes []byte
+}
+
+// ThumbnailBytes scale the picture down to every width, keeping the aspect ratio
+//  @param widths is the width of thumbnails, a width not smaller than the picture is skipped
+//  @param filter is the resampling filter, exp: xdraw.CatmullRom
+//  @param format is the encoding of thumbnails
+//  @return []Thumbnail the thumbnails in the order of widths
+func ThumbnailBytes(imgBytes []byte, widths []int, filter xdraw.Interpolator, format ImageFormat) ([]Thumbnail, error) {
+	img, _, err := image.Decode(bytes.NewReader(imgBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	var thumbnails []Thumbnail
+	size := img.Bounds().Size()
+	for _, width := range widths {
+		if width <= 0 || width >= size.X {
+			continue
+		}
+		w, h := FitSize(size.X, size.Y, width, 0)
+		content, err := EncodeImage(Resize(img, w, h, filter), format)
+		if err != nil {
+			return nil, err
+		}
+		thumbnails = append(thumbnails, Thumbnail{Width: w, Height: h, Bytes: content})
+	}
+	return thumbnails, nil
+}
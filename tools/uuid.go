@@ -0,0 +1,18 @@
+package tools
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// NewUUID generate a random (version 4) UUID string, exp: 3b241101-e2bb-4255-8caf-4136c566a962
+func NewUUID() string {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		panic(err)
+	}
+	u[6] = (u[6] & 0x0f) | 0x40 // version 4
+	u[8] = (u[8] & 0x3f) | 0x80 // variant 10
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
@@ -0,0 +1,120 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"strconv"
+	"strings"
+)
+
+// WatermarkOptions is the style of the provenance banner
+type WatermarkOptions struct {
+	// Position of the banner, top / bottom, default bottom
+	Position string
+	// Scale is the pixel size of the bundled font, the text height is 13*Scale, default 1
+	Scale int
+	// Padding around the text, default 8
+	Padding int
+	// Background color of the banner, default black
+	Background color.Color
+	// Foreground color of the text, default white
+	Foreground color.Color
+}
+
+func (opt WatermarkOptions) withDefault() WatermarkOptions {
+	if opt.Position != "top" {
+		opt.Position = "bottom"
+	}
+	if opt.Scale <= 0 {
+		opt.Scale = 1
+	}
+	if opt.Padding <= 0 {
+		opt.Padding = 8
+	}
+	if opt.Background == nil {
+		opt.Background = color.Black
+	}
+	if opt.Foreground == nil {
+		opt.Foreground = color.White
+	}
+	return opt
+}
+
+// ParseHexColor parse color like #rrggbb or #rrggbbaa
+func ParseHexColor(s string) (color.Color, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(s) != 6 && len(s) != 8 {
+		return nil, fmt.Errorf("invalid color: #%s", s)
+	}
+	if len(s) == 6 {
+		s += "ff"
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid color: #%s", s)
+	}
+	return color.NRGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
+
+// wrapText break the line into lines not wider than width, a long word like url is broken by characters
+func wrapText(line string, width int, scale int) []string {
+	maxChars := width / (glyphWidth * scale)
+	if maxChars < 1 {
+		maxChars = 1
+	}
+	var lines []string
+	runes := []rune(line)
+	for len(runes) > maxChars {
+		cut := maxChars
+		if i := strings.LastIndex(string(runes[:maxChars]), " "); i > 0 {
+			cut = len([]rune(string(runes[:maxChars])[:i]))
+		}
+		lines = append(lines, strings.TrimRight(string(runes[:cut]), " "))
+		runes = []rune(strings.TrimLeft(string(runes[cut:]), " "))
+	}
+	return append(lines, string(runes))
+}
+
+// Watermark add a banner of text lines on the top or bottom of picture.
+// The canvas is extended by the banner, so the captured content is never covered.
+func Watermark(img image.Image, lines []string, opt WatermarkOptions) *image.RGBA {
+	opt = opt.withDefault()
+	b := img.Bounds()
+	textWidth := b.Dx() - 2*opt.Padding
+
+	var wrapped []string
+	for _, line := range lines {
+		wrapped = append(wrapped, wrapText(line, textWidth, opt.Scale)...)
+	}
+	lineHeight := (glyphHeight + 3) * opt.Scale
+	bannerHeight := len(wrapped)*lineHeight + 2*opt.Padding - 3*opt.Scale
+
+	out := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()+bannerHeight))
+	banner := image.Rect(0, b.Dy(), b.Dx(), b.Dy()+bannerHeight)
+	content := image.Rect(0, 0, b.Dx(), b.Dy())
+	if opt.Position == "top" {
+		banner = image.Rect(0, 0, b.Dx(), bannerHeight)
+		content = content.Add(image.Point{Y: bannerHeight})
+	}
+	draw.Draw(out, content, img, b.Min, draw.Src)
+	draw.Draw(out, banner, image.NewUniform(opt.Background), image.Point{}, draw.Src)
+
+	for i, line := range wrapped {
+		DrawText(out, banner.Min.X+opt.Padding, banner.Min.Y+opt.Padding+i*lineHeight, line, opt.Scale, opt.Foreground)
+	}
+	return out
+}
+
+// WatermarkBytes add a banner of text lines on picture byte array
+// imageFormat is the format of output image, png / jpeg
+func WatermarkBytes(imgBytes []byte, lines []string, opt WatermarkOptions, imageFormat string) ([]byte, error) {
+	img, _, err := image.Decode(bytes.NewReader(imgBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	return ImageToBytes(Watermark(img, lines, opt), imageFormat)
+}
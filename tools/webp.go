@@ -0,0 +1,120 @@
+//go:build cgo
+
+package tools
+
+/*
+#cgo linux LDFLAGS: -ldl
+#include <dlfcn.h>
+#include <stdint.h>
+#include <stdlib.h>
+
+typedef size_t (*webpEncodeRGBA)(const uint8_t*, int, int, int, float, uint8_t**);
+typedef size_t (*webpEncodeLosslessRGBA)(const uint8_t*, int, int, int, uint8_t**);
+typedef void (*webpFree)(void*);
+
+static webpEncodeRGBA encodeRGBA;
+static webpEncodeLosslessRGBA encodeLosslessRGBA;
+static webpFree freeOutput;
+
+// loadWebP resolve the simple encoding api of libwebp, 0 if the library is loaded
+static int loadWebP(const char *name) {
+	void *lib = dlopen(name, RTLD_NOW | RTLD_LOCAL);
+	if (lib == NULL) {
+		return -1;
+	}
+	encodeRGBA = (webpEncodeRGBA)dlsym(lib, "WebPEncodeRGBA");
+	encodeLosslessRGBA = (webpEncodeLosslessRGBA)dlsym(lib, "WebPEncodeLosslessRGBA");
+	// WebPFree is missing before libwebp 0.5, the output is allocated by malloc then
+	freeOutput = (webpFree)dlsym(lib, "WebPFree");
+	if (freeOutput == NULL) {
+		freeOutput = free;
+	}
+	if (encodeRGBA == NULL || encodeLosslessRGBA == NULL) {
+		dlclose(lib);
+		return -1;
+	}
+	return 0;
+}
+
+static size_t encodeWebP(const uint8_t *rgba, int width, int height, int stride, float quality, int lossless, uint8_t **output) {
+	if (lossless) {
+		return encodeLosslessRGBA(rgba, width, height, stride, output);
+	}
+	return encodeRGBA(rgba, width, height, stride, quality, output);
+}
+
+static void freeWebP(uint8_t *output) {
+	freeOutput(output);
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// webpLibraries is the shared library of libwebp tried in order, it is loaded at runtime,
+// so that the build needs no webp headers, exp: apt install libwebp7 / brew install webp
+var webpLibraries = []string{"libwebp.so.7", "libwebp.so.6", "libwebp.so", "libwebp.7.dylib", "libwebp.dylib"}
+
+var (
+	webpOnce    sync.Once
+	webpLoadErr error
+)
+
+// loadWebP load libwebp once
+//  @return error if none of webpLibraries is found
+func loadWebP() error {
+	webpOnce.Do(func() {
+		for _, name := range webpLibraries {
+			cName := C.CString(name)
+			loaded := C.loadWebP(cName) == 0
+			C.free(unsafe.Pointer(cName))
+			if loaded {
+				return
+			}
+		}
+		webpLoadErr = fmt.Errorf("webp: libwebp is not found, tried %s", strings.Join(webpLibraries, ", "))
+	})
+	return webpLoadErr
+}
+
+// EncodeWebP encode the picture as a webp by libwebp
+//  @param img the picture, at most 16383 x 16383
+//  @param quality the lossy quality in [0, 100], ignored if lossless
+//  @param lossless whether to keep every pixel
+func EncodeWebP(img image.Image, quality int, lossless bool) ([]byte, error) {
+	if err := loadWebP(); err != nil {
+		return nil, err
+	}
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if width < 1 || height < 1 || width > webpMaxSize || height > webpMaxSize {
+		return nil, fmt.Errorf("webp: the picture should be 1 to %d pixels wide and high", webpMaxSize)
+	}
+	// libwebp reads the non-premultiplied rgba
+	nrgba, ok := img.(*image.NRGBA)
+	if !ok {
+		nrgba = image.NewNRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(nrgba, nrgba.Bounds(), img, b.Min, draw.Src)
+	}
+
+	keep := C.int(0)
+	if lossless {
+		keep = 1
+	}
+	var output *C.uint8_t
+	size := C.encodeWebP((*C.uint8_t)(unsafe.Pointer(&nrgba.Pix[0])), C.int(width), C.int(height), C.int(nrgba.Stride),
+		C.float(quality), keep, &output)
+	if size == 0 || output == nil {
+		return nil, errors.New("webp: libwebp failed to encode the picture")
+	}
+	defer C.freeWebP(output)
+	return C.GoBytes(unsafe.Pointer(output), C.int(size)), nil
+}
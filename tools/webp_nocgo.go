@@ -0,0 +1,18 @@
+//go:build !cgo
+
+package tools
+
+import (
+	"errors"
+	"image"
+)
+
+// loadWebP libwebp is loaded by cgo
+func loadWebP() error {
+	return errors.New("webp: the encoder needs cgo to load libwebp")
+}
+
+// EncodeWebP is not supported without cgo
+func EncodeWebP(img image.Image, quality int, lossless bool) ([]byte, error) {
+	return nil, loadWebP()
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"gopkg.in/ini.v1"
+	"log"
+	"os"
+	"strings"
+	"y-clouds.com/tarantula/evidence"
+	"y-clouds.com/tarantula/tools"
+)
+
+// errInvalidBundle is returned when the signature or an artifact does not match the manifest
+var errInvalidBundle = errors.New("invalid bundle")
+
+// verifyRequest is the flags of verify command
+type verifyRequest struct {
+	conf         string
+	pubKeyPath   string
+	fromOss      bool
+	manifestPath string
+	sigPath      string
+	imagePath    string
+	htmlPath     string
+}
+
+// verifyCommand check a capture bundle against its signature
+//  tarantula verify [-c conf.ini] [-pubkey pub.pem] [-oss] -manifest xxx-manifest.json [-sig xxx-manifest.json.sig] [-image xxx.png] [-html xxx-page.html.gz]
+// With -oss, the manifest, signature and artifacts are object keys read from the oss of config,
+// and the artifacts default to the keys recorded in the manifest.
+//  @return int the exit code, 0 if the bundle is valid, 1 if it is invalid, 2 if it can not be verified
+func verifyCommand(args []string) int {
+	var req verifyRequest
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	fs.StringVar(&req.conf, "c", *confFile, "Snapshot tool configuration file, provides the public key and oss.")
+	fs.StringVar(&req.pubKeyPath, "pubkey", "", "Ed25519 public key pem file, default is Evidence.PublicKey of config.")
+	fs.BoolVar(&req.fromOss, "oss", false, "Read the bundle from oss instead of local files.")
+	fs.StringVar(&req.manifestPath, "manifest", "", "The manifest json.")
+	fs.StringVar(&req.sigPath, "sig", "", "The signature of manifest, default is {manifest}.sig.")
+	fs.StringVar(&req.imagePath, "image", "", "The screenshot to check against the manifest.")
+	fs.StringVar(&req.htmlPath, "html", "", "The page source to check against the manifest.")
+	_ = fs.Parse(args)
+
+	if req.manifestPath == "" {
+		fs.Usage()
+		return 2
+	}
+	if req.sigPath == "" {
+		req.sigPath = req.manifestPath + ".sig"
+	}
+
+	manifest, err := verifyBundle(req)
+	if errors.Is(err, errInvalidBundle) {
+		log.Printf("INVALID %s: %v", req.manifestPath, err)
+		return 1
+	}
+	if err != nil {
+		log.Printf("Verify %s.error: %v", req.manifestPath, err)
+		return 2
+	}
+
+	log.Printf("VALID %s: %s captured at %s by %s, signed by key %s", req.manifestPath, manifest.Url,
+		manifest.CapturedAt.Format("2006-01-02 15:04:05 MST"), manifest.WorkerHost, manifest.KeyId)
+	return 0
+}
+
+// verifyBundle check the signature of manifest, then the artifacts against the manifest
+//  @return *evidence.Manifest the manifest verified
+//  @return error errInvalidBundle if the signature or an artifact does not match
+func verifyBundle(req verifyRequest) (*evidence.Manifest, error) {
+	var cfg *ini.File
+	if _, err := os.Stat(req.conf); err == nil {
+		cfg, err = ini.LoadSources(confLoadOptions, req.conf)
+		if err != nil {
+			return nil, fmt.Errorf("read config %s: %v", req.conf, err)
+		}
+	}
+	if req.pubKeyPath == "" && cfg != nil {
+		req.pubKeyPath = cfg.Section("Evidence").Key("PublicKey").String()
+	}
+	publicKey, err := evidence.LoadPublicKey(req.pubKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("load public key: %v", err)
+	}
+
+	// read from local files or oss
+	read := os.ReadFile
+	if req.fromOss {
+		if cfg == nil {
+			return nil, fmt.Errorf("read config %s: not found", req.conf)
+		}
+		aliOss, err := ossFromConf(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("missing Ali oss configuration parameters: %v", err)
+		}
+		read = aliOss.GetBytesFromOSS
+	}
+
+	content, err := read(req.manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("read manifest: %v", err)
+	}
+	signature, err := read(req.sigPath)
+	if err != nil {
+		return nil, fmt.Errorf("read signature: %v", err)
+	}
+	manifest, err := evidence.Verify(content, signature, publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidBundle, err)
+	}
+
+	// the artifacts recorded in manifest
+	if req.fromOss && req.imagePath == "" {
+		req.imagePath = manifest.Screenshot.Key
+	}
+	if req.fromOss && req.htmlPath == "" {
+		req.htmlPath = manifest.Html.Key
+	}
+	artifacts := []struct {
+		name     string
+		path     string
+		artifact evidence.Artifact
+	}{
+		{"screenshot", req.imagePath, manifest.Screenshot},
+		{"html", req.htmlPath, manifest.Html},
+	}
+	var altered []string
+	for _, a := range artifacts {
+		if a.path == "" {
+			continue
+		}
+		artifactContent, err := read(a.path)
+		if err == nil && strings.HasSuffix(a.path, ".gz") {
+			// the page source is uploaded compressed, the manifest records the raw html
+			artifactContent, err = tools.GunzipBytes(artifactContent)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %v", a.name, err)
+		}
+		if a.artifact.Matches(artifactContent) {
+			log.Printf("%s %s matches sha256 %s", a.name, a.path, a.artifact.Sha256)
+		} else {
+			log.Printf("%s %s does NOT match sha256 %s", a.name, a.path, a.artifact.Sha256)
+			altered = append(altered, a.name)
+		}
+	}
+	if len(altered) > 0 {
+		return nil, fmt.Errorf("%w: %s altered", errInvalidBundle, strings.Join(altered, ", "))
+	}
+	return manifest, nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"y-clouds.com/tarantula/evidence"
+	"y-clouds.com/tarantula/oss/osstest"
+	"y-clouds.com/tarantula/tools"
+)
+
+// testBundle is a signed capture written as local files
+type testBundle struct {
+	dir       string
+	req       verifyRequest
+	publicKey ed25519.PublicKey
+	files     map[string][]byte
+}
+
+// newTestBundle sign a manifest of a screenshot and a gzipped page source, the public key is kept in the config
+func newTestBundle(t *testing.T) *testBundle {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	screenshot, html := []byte("picture"), []byte("<html>page</html>")
+	content, signature, err := evidence.Sign(evidence.Manifest{
+		Url:        "https://www.ebay.com/itm/1",
+		Screenshot: evidence.NewArtifact("ebay/US/1.png", screenshot),
+		Html:       evidence.NewArtifact("ebay/US/1-page.html.gz", html),
+	}, privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gzipped, err := tools.GzipBytes(html)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := &testBundle{dir: t.TempDir(), publicKey: publicKey, files: map[string][]byte{
+		"ebay/US/1-manifest.json":     content,
+		"ebay/US/1-manifest.json.sig": signature,
+		"ebay/US/1.png":               screenshot,
+		"ebay/US/1-page.html.gz":      gzipped,
+	}}
+	for name, content := range b.files {
+		b.write(t, name, content)
+	}
+	b.write(t, "ed25519.pub.pem", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	b.write(t, "conf.ini", []byte(fmt.Sprintf("[Evidence]\nPublicKey = %s\n", b.path("ed25519.pub.pem"))))
+	b.req = verifyRequest{
+		conf:         b.path("conf.ini"),
+		manifestPath: b.path("ebay/US/1-manifest.json"),
+		sigPath:      b.path("ebay/US/1-manifest.json.sig"),
+		imagePath:    b.path("ebay/US/1.png"),
+		htmlPath:     b.path("ebay/US/1-page.html.gz"),
+	}
+	return b
+}
+
+func (b *testBundle) path(name string) string {
+	return filepath.Join(b.dir, name)
+}
+
+func (b *testBundle) write(t *testing.T, name string, content []byte) {
+	if err := os.MkdirAll(filepath.Dir(b.path(name)), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(b.path(name), content, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyBundle(t *testing.T) {
+	b := newTestBundle(t)
+	manifest, err := verifyBundle(b.req)
+	if err != nil {
+		t.Fatalf("verifyBundle() error: %v", err)
+	}
+	if manifest.Url != "https://www.ebay.com/itm/1" || manifest.KeyId != evidence.KeyId(b.publicKey) {
+		t.Errorf("verifyBundle() = %+v, want the signed manifest", manifest)
+	}
+
+	// the artifacts are optional
+	req := b.req
+	req.imagePath, req.htmlPath = "", ""
+	if _, err = verifyBundle(req); err != nil {
+		t.Errorf("verifyBundle() of the manifest only error: %v", err)
+	}
+}
+
+func TestVerifyBundleInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		modify func([]byte) []byte
+	}{
+		{"tampered manifest", "ebay/US/1-manifest.json", func(c []byte) []byte { return append(c, ' ') }},
+		{"tampered screenshot", "ebay/US/1.png", func(c []byte) []byte { return append(c, '!') }},
+		{"tampered page source", "ebay/US/1-page.html.gz", func([]byte) []byte {
+			gzipped, _ := tools.GzipBytes([]byte("<html>another page</html>"))
+			return gzipped
+		}},
+	}
+	for _, tt := range tests {
+		b := newTestBundle(t)
+		b.write(t, tt.file, tt.modify(b.files[tt.file]))
+		if _, err := verifyBundle(b.req); !errors.Is(err, errInvalidBundle) {
+			t.Errorf("verifyBundle() of the %s = %v, want errInvalidBundle", tt.name, err)
+		}
+	}
+
+	// the page source is not gzipped, it can not be read instead of altered
+	b := newTestBundle(t)
+	b.write(t, "ebay/US/1-page.html.gz", []byte("<html>page</html>"))
+	if _, err := verifyBundle(b.req); err == nil || errors.Is(err, errInvalidBundle) {
+		t.Errorf("verifyBundle() of a page source not gzipped = %v, want a read error", err)
+	}
+
+	// signed by another key
+	b = newTestBundle(t)
+	publicKey, _, _ := ed25519.GenerateKey(rand.Reader)
+	der, _ := x509.MarshalPKIXPublicKey(publicKey)
+	b.write(t, "another.pub.pem", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	b.req.pubKeyPath = b.path("another.pub.pem")
+	if _, err := verifyBundle(b.req); !errors.Is(err, errInvalidBundle) {
+		t.Errorf("verifyBundle() by another key = %v, want errInvalidBundle", err)
+	}
+
+	// the key can not be loaded
+	b = newTestBundle(t)
+	b.req.pubKeyPath = b.path("missing.pem")
+	if _, err := verifyBundle(b.req); err == nil || errors.Is(err, errInvalidBundle) {
+		t.Errorf("verifyBundle() without a key = %v, want a load error", err)
+	}
+}
+
+func TestVerifyBundleFromOss(t *testing.T) {
+	b := newTestBundle(t)
+	bucket := osstest.NewBucket(t)
+	for name, content := range b.files {
+		bucket.Put(name, content)
+	}
+	conf := fmt.Sprintf("[Evidence]\nPublicKey = %s\n[OSS]\nEndpoint = %s\nAccessID = id\nAccessKey = key\nBucketName = %s\n",
+		b.path("ed25519.pub.pem"), bucket.Endpoint, bucket.Name)
+	b.write(t, "conf.ini", []byte(conf))
+
+	// the artifacts default to the keys recorded in the manifest
+	req := verifyRequest{conf: b.req.conf, fromOss: true, manifestPath: "ebay/US/1-manifest.json", sigPath: "ebay/US/1-manifest.json.sig"}
+	if _, err := verifyBundle(req); err != nil {
+		t.Fatalf("verifyBundle() from oss error: %v", err)
+	}
+
+	bucket.Put("ebay/US/1.png", []byte("another picture"))
+	if _, err := verifyBundle(req); !errors.Is(err, errInvalidBundle) {
+		t.Errorf("verifyBundle() from oss of a tampered screenshot = %v, want errInvalidBundle", err)
+	}
+}